@@ -0,0 +1,301 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	k8smeta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Target is what the steps of a composite action are done to: a pod and the node it runs on, or
+// just a node
+type Target struct {
+	// nil if the target is a node
+	Pod *v1.Pod
+	// the name of the node
+	NodeName string
+}
+
+func (t Target) String() string {
+	if t.Pod != nil {
+		return t.Pod.Namespace + "/" + t.Pod.Name
+	}
+	return t.NodeName
+}
+
+// Step is one step of a composite action
+type Step interface {
+	// Called once at startup, do any initial setup here
+	Init(ctx context.Context, client kubernetes.Interface) error
+	// Do the step to the target
+	Apply(ctx context.Context, client kubernetes.Interface, target Target) error
+	// Undo the step, after a later one failed; steps that can't be undone do nothing
+	Revert(ctx context.Context, client kubernetes.Interface, target Target) error
+	// Name of this step, like "wait 2m0s"
+	Name() string
+}
+
+// Condition tells whether something holds for the target of a composite action, given when the
+// step waiting for it started
+type Condition func(client kubernetes.Interface, target Target, since time.Time) (bool, error)
+
+// NewCompositePodAction returns an action doing the steps to a pod and its node, one after
+// another. When a step fails, the steps done before it are reverted, last first.
+func NewCompositePodAction(steps []Step, logger log.FieldLogger) PodAction {
+	return &composite{steps: steps, logger: logger}
+}
+
+// NewCompositeNodeAction returns an action doing the steps to a node, one after another. When a
+// step fails, the steps done before it are reverted, last first.
+func NewCompositeNodeAction(steps []Step, logger log.FieldLogger) NodeAction {
+	return &composite{steps: steps, logger: logger}
+}
+
+type composite struct {
+	steps  []Step
+	logger log.FieldLogger
+	// the client pod steps are done with, set by Init
+	client kubernetes.Interface
+}
+
+func (a *composite) Init(ctx context.Context, client kubernetes.Interface) error {
+	a.client = client
+	for _, step := range a.steps {
+		if err := step.Init(ctx, client); err != nil {
+			return fmt.Errorf("failed to initialize %s: %s", step.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (a *composite) ApplyToPod(ctx context.Context, victim v1.Pod) error {
+	return a.apply(ctx, a.client, Target{Pod: &victim, NodeName: victim.Spec.NodeName})
+}
+
+func (a *composite) ApplyToNode(ctx context.Context, client kubernetes.Interface, victim *v1.Node) error {
+	return a.apply(ctx, client, Target{NodeName: victim.Name})
+}
+
+func (a *composite) Name() string {
+	names := make([]string, len(a.steps))
+	for i, step := range a.steps {
+		names[i] = step.Name()
+	}
+	return strings.Join(names, ", then ")
+}
+
+// apply does the steps in order, rolling back the ones done when one fails
+func (a *composite) apply(ctx context.Context, client kubernetes.Interface, target Target) error {
+	logger := a.logger.WithField("target", target.String())
+
+	for i, step := range a.steps {
+		logger.WithField("step", step.Name()).Debug("applying step")
+		err := step.Apply(ctx, client, target)
+		if err == nil {
+			continue
+		}
+
+		err = fmt.Errorf("step %d of %d, %s, failed: %s", i+1, len(a.steps), step.Name(), err)
+		return a.rollback(client, target, a.steps[:i], err, logger)
+	}
+	return nil
+}
+
+// rollback reverts the steps done, last first, adding any failure to revert to the error
+func (a *composite) rollback(client kubernetes.Interface, target Target, done []Step, err error, logger log.FieldLogger) error {
+	// deliberately not bound to the context of the action, which may be what failed it
+	ctx, cancel := context.WithTimeout(context.Background(), revertTimeout)
+	defer cancel()
+
+	for i := len(done) - 1; i >= 0; i-- {
+		logger.WithField("step", done[i].Name()).Info("reverting step")
+		if revertErr := done[i].Revert(ctx, client, target); revertErr != nil {
+			err = fmt.Errorf("%s; failed to revert %s: %s", err, done[i].Name(), revertErr)
+		}
+	}
+	return err
+}
+
+// NewPodStep returns a step doing a pod action to the target pod, reverted if the action is a
+// PodReverter
+func NewPodStep(action PodAction) Step {
+	return &podStep{action}
+}
+
+type podStep struct {
+	action PodAction
+}
+
+func (s *podStep) Init(ctx context.Context, client kubernetes.Interface) error {
+	return s.action.Init(ctx, client)
+}
+func (s *podStep) Apply(ctx context.Context, client kubernetes.Interface, target Target) error {
+	if target.Pod == nil {
+		return fmt.Errorf("node %s is no pod to %s", target.NodeName, s.action.Name())
+	}
+	return s.action.ApplyToPod(ctx, *target.Pod)
+}
+func (s *podStep) Revert(ctx context.Context, client kubernetes.Interface, target Target) error {
+	if reverter, ok := s.action.(PodReverter); ok && target.Pod != nil {
+		return reverter.RevertPod(ctx, *target.Pod)
+	}
+	return nil
+}
+func (s *podStep) Name() string { return s.action.Name() }
+
+// NewNodeStep returns a step doing a node action to the node of the target, reverted if the action
+// is a NodeReverter
+func NewNodeStep(action NodeAction) Step {
+	return &nodeStep{action}
+}
+
+type nodeStep struct {
+	action NodeAction
+}
+
+func (s *nodeStep) Init(ctx context.Context, client kubernetes.Interface) error {
+	return s.action.Init(ctx, client)
+}
+func (s *nodeStep) Apply(ctx context.Context, client kubernetes.Interface, target Target) error {
+	node, err := targetNode(client, target)
+	if err != nil {
+		return err
+	}
+	return s.action.ApplyToNode(ctx, client, node)
+}
+func (s *nodeStep) Revert(ctx context.Context, client kubernetes.Interface, target Target) error {
+	reverter, ok := s.action.(NodeReverter)
+	if !ok {
+		return nil
+	}
+	node, err := targetNode(client, target)
+	if err != nil {
+		return err
+	}
+	return reverter.RevertNode(ctx, client, node)
+}
+func (s *nodeStep) Name() string { return s.action.Name() }
+
+// targetNode fetches the node of the target as it is now
+func targetNode(client kubernetes.Interface, target Target) (*v1.Node, error) {
+	if target.NodeName == "" {
+		return nil, fmt.Errorf("pod %s is not on a node", target)
+	}
+	return client.CoreV1().Nodes().Get(target.NodeName, k8smeta.GetOptions{})
+}
+
+// NewWaitStep returns a step doing nothing for a while
+func NewWaitStep(duration time.Duration) Step {
+	return &waitStep{duration}
+}
+
+type waitStep struct {
+	duration time.Duration
+}
+
+func (s *waitStep) Init(ctx context.Context, client kubernetes.Interface) error {
+	return nil
+}
+func (s *waitStep) Apply(ctx context.Context, client kubernetes.Interface, target Target) error {
+	select {
+	case <-time.After(s.duration):
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("gave up waiting: %s", ctx.Err())
+	}
+}
+func (s *waitStep) Revert(ctx context.Context, client kubernetes.Interface, target Target) error {
+	return nil
+}
+func (s *waitStep) Name() string { return fmt.Sprintf("wait %s", s.duration) }
+
+// NewUntilStep returns a step waiting until the condition holds, failing if it doesn't within the
+// timeout. How long it took is logged, as the time the target took to recover.
+func NewUntilStep(name string, condition Condition, timeout time.Duration, logger log.FieldLogger) Step {
+	return &untilStep{name: name, condition: condition, timeout: timeout, interval: 2 * time.Second, logger: logger}
+}
+
+type untilStep struct {
+	name      string
+	condition Condition
+	timeout   time.Duration
+	// how often the condition is checked
+	interval time.Duration
+	logger   log.FieldLogger
+}
+
+func (s *untilStep) Init(ctx context.Context, client kubernetes.Interface) error {
+	return nil
+}
+func (s *untilStep) Apply(ctx context.Context, client kubernetes.Interface, target Target) error {
+	since := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	err := wait.PollImmediateUntil(s.interval, func() (bool, error) {
+		return s.condition(client, target, since)
+	}, ctx.Done())
+	if err == wait.ErrWaitTimeout && ctx.Err() != nil {
+		return fmt.Errorf("gave up waiting until %s: %s", s.name, ctx.Err())
+	}
+	if err != nil {
+		return err
+	}
+
+	s.logger.WithFields(log.Fields{
+		"target":   target.String(),
+		"recovery": time.Since(since).String(),
+	}).Info(s.name)
+	return nil
+}
+func (s *untilStep) Revert(ctx context.Context, client kubernetes.Interface, target Target) error {
+	return nil
+}
+func (s *untilStep) Name() string { return "until " + s.name }
+
+// PodReplaced holds once a pod of the controller of the target pod, other than the target and
+// created since the step started, is Ready
+func PodReplaced(client kubernetes.Interface, target Target, since time.Time) (bool, error) {
+	if target.Pod == nil {
+		return false, fmt.Errorf("node %s is no pod to be replaced", target.NodeName)
+	}
+	controller := ControllerOf(target.Pod.OwnerReferences)
+	if controller == nil {
+		return false, fmt.Errorf("pod %s has no controller to replace it", target)
+	}
+
+	pods, err := client.CoreV1().Pods(target.Pod.Namespace).List(k8smeta.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+	// creation timestamps are only precise to the second
+	since = since.Truncate(time.Second)
+	for _, pod := range pods.Items {
+		owner := ControllerOf(pod.OwnerReferences)
+		if owner == nil || owner.UID != controller.UID || pod.UID == target.Pod.UID {
+			continue
+		}
+		if !pod.CreationTimestamp.Time.Before(since) && isReady(&pod) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func isReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+var _ PodAction = &composite{}
+var _ NodeAction = &composite{}
@@ -0,0 +1,161 @@
+package action_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neo-technology/marmoset/chaoskube/action"
+	"k8s.io/api/core/v1"
+	k8smeta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// recordingPodAction records what is done with it, and fails if told to
+type recordingPodAction struct {
+	name     string
+	fail     bool
+	recorded *[]string
+}
+
+func (a *recordingPodAction) Init(ctx context.Context, client kubernetes.Interface) error {
+	return nil
+}
+func (a *recordingPodAction) ApplyToPod(ctx context.Context, victim v1.Pod) error {
+	*a.recorded = append(*a.recorded, "apply "+a.name)
+	if a.fail {
+		return fmt.Errorf("%s failed", a.name)
+	}
+	return nil
+}
+func (a *recordingPodAction) RevertPod(ctx context.Context, victim v1.Pod) error {
+	*a.recorded = append(*a.recorded, "revert "+a.name)
+	return nil
+}
+func (a *recordingPodAction) Name() string { return a.name }
+
+func cordoned(t *testing.T, client kubernetes.Interface, name string) bool {
+	node, err := client.CoreV1().Nodes().Get(name, k8smeta.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return node.Spec.Unschedulable || node.Labels[action.LabelMarmosetCordoned] != ""
+}
+
+func TestCompositePodAction(t *testing.T) {
+	node := &v1.Node{ObjectMeta: k8smeta.ObjectMeta{Name: "test-node"}}
+	pod := newPodOnNode("p1", node.Name)
+	client := fake.NewSimpleClientset(node, pod)
+
+	act := action.NewCompositePodAction([]action.Step{
+		action.NewNodeStep(action.NewCordonNodeAction()),
+		action.NewWaitStep(10 * time.Millisecond),
+		action.NewNodeStep(action.NewUncordonNodeAction()),
+	}, pluginLogger())
+	if act.Name() != "cordon, then wait 10ms, then uncordon" {
+		t.Errorf("Unexpected name: %s", act.Name())
+	}
+	if err := act.Init(context.Background(), client); err != nil {
+		t.Fatalf("Init failed with: %s", err)
+	}
+
+	if err := act.ApplyToPod(context.Background(), *pod); err != nil {
+		t.Fatalf("ApplyToPod failed with: %s", err)
+	}
+	if cordoned(t, client, node.Name) {
+		t.Errorf("Expected node to be uncordoned by the last step")
+	}
+}
+
+func TestCompositeActionRollsBack(t *testing.T) {
+	node := &v1.Node{ObjectMeta: k8smeta.ObjectMeta{Name: "test-node"}}
+	pod := newPodOnNode("p1", node.Name)
+	client := fake.NewSimpleClientset(node, pod)
+	recorded := []string{}
+
+	act := action.NewCompositePodAction([]action.Step{
+		action.NewPodStep(&recordingPodAction{name: "first", recorded: &recorded}),
+		action.NewNodeStep(action.NewCordonNodeAction()),
+		action.NewPodStep(&recordingPodAction{name: "second", recorded: &recorded}),
+		action.NewPodStep(&recordingPodAction{name: "third", fail: true, recorded: &recorded}),
+		action.NewPodStep(&recordingPodAction{name: "never", recorded: &recorded}),
+	}, pluginLogger())
+	if err := act.Init(context.Background(), client); err != nil {
+		t.Fatalf("Init failed with: %s", err)
+	}
+
+	err := act.ApplyToPod(context.Background(), *pod)
+	if err == nil || !strings.Contains(err.Error(), "step 4 of 5, third, failed: third failed") {
+		t.Errorf("Expected the third step to fail, actual: %v", err)
+	}
+
+	expected := "apply first, apply second, apply third, revert second, revert first"
+	if strings.Join(recorded, ", ") != expected {
+		t.Errorf("Expected %s, actual: %s", expected, strings.Join(recorded, ", "))
+	}
+	if cordoned(t, client, node.Name) {
+		t.Errorf("Expected node to be uncordoned by the rollback")
+	}
+}
+
+func TestCompositeActionCancelledWhileWaiting(t *testing.T) {
+	node := &v1.Node{ObjectMeta: k8smeta.ObjectMeta{Name: "test-node"}}
+	client := fake.NewSimpleClientset(node)
+
+	act := action.NewCompositeNodeAction([]action.Step{
+		action.NewNodeStep(action.NewCordonNodeAction()),
+		action.NewWaitStep(time.Minute),
+	}, pluginLogger())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := act.ApplyToNode(ctx, client, node)
+	if err == nil || !strings.Contains(err.Error(), "gave up waiting") {
+		t.Errorf("Expected the wait to be cancelled, actual: %v", err)
+	}
+	if cordoned(t, client, node.Name) {
+		t.Errorf("Expected node to be uncordoned by the rollback")
+	}
+}
+
+func TestUntilPodReplaced(t *testing.T) {
+	controller := true
+	owner := []k8smeta.OwnerReference{{Kind: action.KindReplicaSet, Name: "rs", UID: types.UID("rs-1"), Controller: &controller}}
+
+	victim := newPodOnNode("victim", "test-node")
+	victim.UID = types.UID("victim")
+	victim.OwnerReferences = owner
+	sibling := newPodOnNode("sibling", "test-node")
+	sibling.UID = types.UID("sibling")
+	sibling.OwnerReferences = owner
+	sibling.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+	replacement := newPodOnNode("replacement", "test-node")
+	replacement.UID = types.UID("replacement")
+	replacement.OwnerReferences = owner
+	replacement.CreationTimestamp = k8smeta.NewTime(time.Now().Add(time.Minute))
+
+	step := action.NewUntilStep("replaced", action.PodReplaced, 50*time.Millisecond, pluginLogger())
+	target := action.Target{Pod: victim, NodeName: victim.Spec.NodeName}
+
+	// a Ready sibling from before doesn't count, nor does a replacement that isn't Ready yet
+	client := fake.NewSimpleClientset(victim, sibling, replacement)
+	err := step.Apply(context.Background(), client, target)
+	if err == nil || !strings.Contains(err.Error(), "gave up waiting until replaced") {
+		t.Errorf("Expected to give up waiting, actual: %v", err)
+	}
+
+	replacement.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+	client = fake.NewSimpleClientset(victim, sibling, replacement)
+	if err := step.Apply(context.Background(), client, target); err != nil {
+		t.Errorf("Expected the replacement to be found, actual: %v", err)
+	}
+
+	victim.OwnerReferences = nil
+	if err := step.Apply(context.Background(), client, target); err == nil {
+		t.Errorf("Expected a bare pod to have no replacement")
+	}
+}
@@ -0,0 +1,57 @@
+package action
+
+import (
+	"context"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func NewCordonNodeAction() NodeAction {
+	return &cordonOnly{}
+}
+
+func NewUncordonNodeAction() NodeAction {
+	return &uncordonOnly{}
+}
+
+// Mark the victim unschedulable, and labelled so a crashed marmoset uncordons it on restart; meant
+// as a step of a composite action, which reverts it by uncordoning
+type cordonOnly struct{}
+
+func (a *cordonOnly) Init(ctx context.Context, client kubernetes.Interface) error {
+	return crashRecoverNodeDrain(ctx, client)
+}
+func (a *cordonOnly) ApplyToNode(ctx context.Context, client kubernetes.Interface, victim *v1.Node) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, err := cordonNode(client, victim.DeepCopy())
+	return err
+}
+func (a *cordonOnly) RevertNode(ctx context.Context, client kubernetes.Interface, victim *v1.Node) error {
+	_, err := uncordonNode(client, victim.DeepCopy())
+	return err
+}
+func (a *cordonOnly) Name() string {
+	return "cordon"
+}
+
+// Make the victim schedulable again
+type uncordonOnly struct{}
+
+func (a *uncordonOnly) Init(ctx context.Context, client kubernetes.Interface) error {
+	return nil
+}
+func (a *uncordonOnly) ApplyToNode(ctx context.Context, client kubernetes.Interface, victim *v1.Node) error {
+	// deliberately ignores the context, like the uncordon after a drain
+	_, err := uncordonNode(client, victim.DeepCopy())
+	return err
+}
+func (a *uncordonOnly) Name() string {
+	return "uncordon"
+}
+
+var _ NodeAction = &cordonOnly{}
+var _ NodeReverter = &cordonOnly{}
+var _ NodeAction = &uncordonOnly{}
@@ -1,6 +1,8 @@
 package action
 
 import (
+	"context"
+
 	"k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -11,10 +13,13 @@ func NewDeleteNodeAction() NodeAction {
 
 type deleteNode struct{}
 
-func (s *deleteNode) Init(k8sclient kubernetes.Interface) error {
+func (s *deleteNode) Init(ctx context.Context, k8sclient kubernetes.Interface) error {
 	return nil
 }
-func (a *deleteNode) ApplyToNode(client kubernetes.Interface, victim *v1.Node) error {
+func (a *deleteNode) ApplyToNode(ctx context.Context, client kubernetes.Interface, victim *v1.Node) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	return client.CoreV1().Nodes().Delete(victim.Name, nil)
 }
 func (a *deleteNode) Name() string {
@@ -1,6 +1,7 @@
 package action_test
 
 import (
+	"context"
 	"github.com/neo-technology/marmoset/chaoskube/action"
 	"k8s.io/api/core/v1"
 	k8smeta "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -22,7 +23,7 @@ func TestDeleteNodeAction(t *testing.T) {
 	client := fake.NewSimpleClientset(node, noTouching)
 	act := action.NewDeleteNodeAction()
 
-	err := act.ApplyToNode(client, node)
+	err := act.ApplyToNode(context.Background(), client, node)
 
 	if err != nil {
 		t.Fatalf("Expected smooth sailing, got: %s", err)
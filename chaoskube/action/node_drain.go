@@ -1,6 +1,7 @@
 package action
 
 import (
+	"context"
 	"fmt"
 	"k8s.io/api/core/v1"
 	k8spolicy "k8s.io/api/policy/v1beta1"
@@ -23,16 +24,17 @@ func NewDrainNodeAction() NodeAction {
 
 type drainNode struct{}
 
-func (s *drainNode) Init(client kubernetes.Interface) error {
-	return crashRecoverNodeDrain(client)
+func (s *drainNode) Init(ctx context.Context, client kubernetes.Interface) error {
+	return crashRecoverNodeDrain(ctx, client)
 }
-func (a *drainNode) ApplyToNode(client kubernetes.Interface, victim *v1.Node) (err error) {
+func (a *drainNode) ApplyToNode(ctx context.Context, client kubernetes.Interface, victim *v1.Node) (err error) {
 	victim = victim.DeepCopy()
-	if err = crashRecoverNodeDrain(client); err != nil {
+	if err = crashRecoverNodeDrain(ctx, client); err != nil {
 		return err
 	}
 
-	// No matter what, try to uncordon the node before we're done here
+	// No matter what, try to uncordon the node before we're done here; this deliberately ignores
+	// the context, so a cancelled drain doesn't leave the node cordoned
 	defer func() {
 		_, deferErr := uncordonNode(client, victim)
 		// If there's no other error, set the return error to be whatever the outcome
@@ -49,7 +51,7 @@ func (a *drainNode) ApplyToNode(client kubernetes.Interface, victim *v1.Node) (e
 	}
 
 	// Create evictions for all non-daemon nodes
-	if err = evictAllPodsOnNode(client, victim); err != nil {
+	if err = evictAllPodsOnNode(ctx, client, victim); err != nil {
 		return err
 	}
 
@@ -98,8 +100,8 @@ func updateNode(client kubernetes.Interface, node *v1.Node, changes func(*v1.Nod
 }
 
 // Evict all pods on the given node, respecting PDBs etc.
-// block until all pods evicted, error or 10-minute timeout
-func evictAllPodsOnNode(client kubernetes.Interface, victim *v1.Node) error {
+// block until all pods evicted, error, 10-minute timeout or the context is done
+func evictAllPodsOnNode(ctx context.Context, client kubernetes.Interface, victim *v1.Node) error {
 	pods, err := client.CoreV1().Pods(k8smeta.NamespaceAll).List(k8smeta.ListOptions{
 		FieldSelector: fields.SelectorFromSet(fields.Set{"spec.nodeName": victim.Name}).String()})
 	if err != nil {
@@ -117,13 +119,16 @@ func evictAllPodsOnNode(client kubernetes.Interface, victim *v1.Node) error {
 	}
 
 	for _, pod := range victims {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 		if err = evictPod(client, &pod); err != nil {
 			return fmt.Errorf("unable to evict pod %s: %s", pod.Name, err)
 		}
 	}
 
 	// Wait for evictions to take effect
-	if err = waitForDelete(client, victims, 1*time.Minute, 10*time.Minute); err != nil {
+	if err = waitForDelete(ctx, client, victims, 1*time.Minute, 10*time.Minute); err != nil {
 		return err
 	}
 	return nil
@@ -145,8 +150,11 @@ func evictPod(client kubernetes.Interface, pod *v1.Pod) error {
 	return client.PolicyV1beta1().Evictions(eviction.Namespace).Evict(eviction)
 }
 
-func waitForDelete(client kubernetes.Interface, pods []v1.Pod, interval, timeout time.Duration) error {
-	return wait.PollImmediate(interval, timeout, func() (bool, error) {
+func waitForDelete(ctx context.Context, client kubernetes.Interface, pods []v1.Pod, interval, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := wait.PollImmediateUntil(interval, func() (bool, error) {
 		pendingPods := make([]v1.Pod, 0)
 		for i, pod := range pods {
 			p, err := client.CoreV1().Pods(pod.Namespace).Get(pod.Name, k8smeta.GetOptions{})
@@ -163,12 +171,19 @@ func waitForDelete(client kubernetes.Interface, pods []v1.Pod, interval, timeout
 			return false, nil
 		}
 		return true, nil
-	})
+	}, ctx.Done())
+	if err == wait.ErrWaitTimeout && ctx.Err() != nil {
+		return fmt.Errorf("gave up waiting for %d evicted pods to go: %s", len(pods), ctx.Err())
+	}
+	return err
 }
 
 // To guard against us crashing in the middle of draining a node and not uncordoning it,
 // this finds any node with our marker label and uncordons them.
-func crashRecoverNodeDrain(client kubernetes.Interface) error {
+func crashRecoverNodeDrain(ctx context.Context, client kubernetes.Interface) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	nodeList, err := client.CoreV1().Nodes().List(k8smeta.ListOptions{LabelSelector: fmt.Sprintf("%s=true", LabelMarmosetCordoned)})
 	if err != nil {
 		return err
@@ -182,10 +197,5 @@ func crashRecoverNodeDrain(client kubernetes.Interface) error {
 }
 
 func isDaemon(pod *v1.Pod) bool {
-	for _, owner := range pod.OwnerReferences {
-		if owner.Kind == "DaemonSet" {
-			return true
-		}
-	}
-	return false
+	return HasOwnerOfKind(pod.OwnerReferences, KindDaemonSet)
 }
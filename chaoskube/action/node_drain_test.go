@@ -1,6 +1,7 @@
 package action_test
 
 import (
+	"context"
 	"fmt"
 	"github.com/neo-technology/marmoset/chaoskube/action"
 	"github.com/neo-technology/marmoset/util"
@@ -16,6 +17,7 @@ import (
 	k8sfakepolicy "k8s.io/client-go/kubernetes/typed/policy/v1beta1/fake"
 	k8stesting "k8s.io/client-go/testing"
 	"testing"
+	"time"
 )
 
 func TestDrainNode(t *testing.T) {
@@ -40,7 +42,7 @@ func TestDrainNode(t *testing.T) {
 	act := action.NewDrainNodeAction()
 
 	// When I apply the drain action..
-	err := act.ApplyToNode(client, node)
+	err := act.ApplyToNode(context.Background(), client, node)
 	if err != nil {
 		t.Fatalf("ApplyToNode failed with: %s", err)
 	}
@@ -98,6 +100,37 @@ func TestDrainNode(t *testing.T) {
 	}
 }
 
+func TestDrainNodeUncordonsWhenCancelled(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: k8smeta.ObjectMeta{
+			Name: "test-node",
+		},
+	}
+	stuckPod := newPodOnNode("p1", node.Name)
+
+	client := fixPolicyFake(fake.NewSimpleClientset(node, stuckPod))
+	// Evictions are accepted, but the pod never goes
+	client.Fake.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, nil
+	})
+	act := action.NewDrainNodeAction()
+
+	// When I apply the drain action and give up on it while it waits for the pod to go..
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := act.ApplyToNode(ctx, client, node)
+	if err == nil {
+		t.Fatalf("Expected ApplyToNode to fail when cancelled")
+	}
+
+	// Then the node is uncordoned nonetheless
+	actions := client.Fake.Actions()
+	uncordonNode := actions[len(actions)-1].(k8stesting.UpdateAction).GetObject().(*v1.Node)
+	if uncordonNode.Labels[action.LabelMarmosetCordoned] != "" || uncordonNode.Spec.Unschedulable {
+		t.Errorf("Expected node to be uncordoned, found %v", uncordonNode)
+	}
+}
+
 func TestDrainNodeUncordonsAnyPartiallyDrainedNode(t *testing.T) {
 	victim := &v1.Node{
 		ObjectMeta: k8smeta.ObjectMeta{
@@ -117,7 +150,7 @@ func TestDrainNodeUncordonsAnyPartiallyDrainedNode(t *testing.T) {
 	act := action.NewDrainNodeAction()
 
 	// When I apply the drain action..
-	err := act.ApplyToNode(client, victim)
+	err := act.ApplyToNode(context.Background(), client, victim)
 	if err != nil {
 		t.Fatalf("ApplyToNode failed with: %s", err)
 	}
@@ -150,7 +183,7 @@ func TestInitDrainNodeUncordonsAnyPartiallyDrainedNode(t *testing.T) {
 	act := action.NewDrainNodeAction()
 
 	// When I apply the drain action..
-	err := act.Init(client)
+	err := act.Init(context.Background(), client)
 	if err != nil {
 		t.Fatalf("Init failed with: %s", err)
 	}
@@ -190,7 +223,7 @@ func TestUncordonHandlesOutOfDateNodeObject(t *testing.T) {
 	act := action.NewDrainNodeAction()
 
 	// When I apply the drain action..
-	err := act.Init(client)
+	err := act.Init(context.Background(), client)
 	if err != nil {
 		t.Fatalf("Init failed with: %s", err)
 	}
@@ -0,0 +1,35 @@
+package action
+
+import (
+	k8smeta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Kinds of the owners of pods
+	KindDaemonSet   = "DaemonSet"
+	KindReplicaSet  = "ReplicaSet"
+	KindDeployment  = "Deployment"
+	KindStatefulSet = "StatefulSet"
+	KindJob         = "Job"
+	KindCronJob     = "CronJob"
+)
+
+// HasOwnerOfKind tells whether any of the owner references is of the given kind, e.g. DaemonSet
+func HasOwnerOfKind(owners []k8smeta.OwnerReference, kind string) bool {
+	for _, owner := range owners {
+		if owner.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ControllerOf returns the owner reference that is the controller, or nil if there is none
+func ControllerOf(owners []k8smeta.OwnerReference) *k8smeta.OwnerReference {
+	for i := range owners {
+		if owners[i].Controller != nil && *owners[i].Controller {
+			return &owners[i]
+		}
+	}
+	return nil
+}
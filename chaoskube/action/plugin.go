@@ -0,0 +1,203 @@
+package action
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// Commands a plugin is invoked with, as its only argument
+	PluginInit   = "init"
+	PluginApply  = "apply"
+	PluginRevert = "revert"
+
+	// Kinds of victims a plugin imbues chaos in
+	PluginKindPod  = "pod"
+	PluginKindNode = "node"
+)
+
+// Plugin is an external binary imbuing chaos in pods or nodes. It is invoked with the command as its
+// only argument and a PluginRequest on its stdin, and answers with a PluginResult on its stdout.
+// Plugins with nothing to do for a command may just exit 0 without output.
+type Plugin struct {
+	// the name the plugin is selected by, and the name of its action
+	Name string
+	// PluginKindPod or PluginKindNode
+	Kind string
+	// path to the binary
+	Path string
+}
+
+// ParsePlugin parses a plugin as <name>=<kind>:<path>, e.g. reboot-vm=node:/opt/plugins/reboot-vm
+func ParsePlugin(plugin string) (Plugin, error) {
+	parts := strings.SplitN(plugin, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return Plugin{}, fmt.Errorf("Invalid plugin '%v': must be <name>=<kind>:<path>", plugin)
+	}
+	target := strings.SplitN(parts[1], ":", 2)
+	if len(target) != 2 || target[1] == "" {
+		return Plugin{}, fmt.Errorf("Invalid plugin '%v': must be <name>=<kind>:<path>", plugin)
+	}
+	if target[0] != PluginKindPod && target[0] != PluginKindNode {
+		return Plugin{}, fmt.Errorf("Invalid plugin '%v': kind must be pod or node", plugin)
+	}
+	return Plugin{Name: strings.TrimSpace(parts[0]), Kind: target[0], Path: target[1]}, nil
+}
+
+// PluginRequest is what a plugin reads from its stdin
+type PluginRequest struct {
+	// PluginInit, PluginApply or PluginRevert
+	Command string `json:"command"`
+	// the name of the plugin
+	Action string `json:"action"`
+	// the victim, if any: a pod for pod plugins, a node for node plugins
+	Pod  *v1.Pod  `json:"pod,omitempty"`
+	Node *v1.Node `json:"node,omitempty"`
+}
+
+// PluginResult is what a plugin writes to its stdout
+type PluginResult struct {
+	// why the command failed; it succeeded if empty and the plugin exited 0
+	Error string `json:"error,omitempty"`
+	// what the plugin did, to log
+	Message string `json:"message,omitempty"`
+}
+
+func NewPluginPodAction(plugin Plugin, logger log.FieldLogger) PodAction {
+	return &pluginAction{plugin, logger}
+}
+
+func NewPluginNodeAction(plugin Plugin, logger log.FieldLogger) NodeAction {
+	return &pluginAction{plugin, logger}
+}
+
+// Imbue chaos by running a plugin. A failed or cancelled apply is reverted.
+type pluginAction struct {
+	plugin Plugin
+	logger log.FieldLogger
+}
+
+func (a *pluginAction) Init(ctx context.Context, k8sclient kubernetes.Interface) error {
+	return a.run(ctx, PluginRequest{Command: PluginInit}, a.logger)
+}
+
+func (a *pluginAction) ApplyToPod(ctx context.Context, victim v1.Pod) error {
+	logger := a.logger.WithFields(log.Fields{"namespace": victim.Namespace, "name": victim.Name})
+	return a.apply(ctx, PluginRequest{Pod: &victim}, logger)
+}
+
+func (a *pluginAction) ApplyToNode(ctx context.Context, client kubernetes.Interface, victim *v1.Node) error {
+	return a.apply(ctx, PluginRequest{Node: victim}, a.logger.WithField("name", victim.Name))
+}
+
+// RevertPod undoes what the plugin did to the pod
+func (a *pluginAction) RevertPod(ctx context.Context, victim v1.Pod) error {
+	logger := a.logger.WithFields(log.Fields{"namespace": victim.Namespace, "name": victim.Name})
+	return a.run(ctx, PluginRequest{Command: PluginRevert, Pod: &victim}, logger)
+}
+
+// RevertNode undoes what the plugin did to the node
+func (a *pluginAction) RevertNode(ctx context.Context, client kubernetes.Interface, victim *v1.Node) error {
+	return a.run(ctx, PluginRequest{Command: PluginRevert, Node: victim}, a.logger.WithField("name", victim.Name))
+}
+
+func (a *pluginAction) Name() string {
+	return a.plugin.Name
+}
+
+// apply runs the plugin on a victim, reverting what it did if it fails
+func (a *pluginAction) apply(ctx context.Context, request PluginRequest, logger log.FieldLogger) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	request.Command = PluginApply
+	err := a.run(ctx, request, logger)
+	if err == nil {
+		return nil
+	}
+
+	// deliberately not bound to ctx: a cancelled apply is reverted all the more
+	revertCtx, cancel := context.WithTimeout(context.Background(), revertTimeout)
+	defer cancel()
+	request.Command = PluginRevert
+	if revertErr := a.run(revertCtx, request, logger); revertErr != nil {
+		logger.WithField("err", revertErr).Error("failed to revert plugin")
+	}
+	return err
+}
+
+// run invokes the plugin with a request, and logs what it answers
+func (a *pluginAction) run(ctx context.Context, request PluginRequest, logger log.FieldLogger) error {
+	request.Action = a.plugin.Name
+	input, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(a.plugin.Path, request.Command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// in a process group of its own, so whatever a script started can be killed with it
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	logger = logger.WithFields(log.Fields{"plugin": a.plugin.Name, "command": request.Command})
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("plugin %s failed to %s: %s", a.plugin.Name, request.Command, err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	var runErr error
+	select {
+	case runErr = <-done:
+	case <-ctx.Done():
+		// killing just the plugin would leave its children holding stdout open, and Wait waiting
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		runErr = <-done
+	}
+	if stderr.Len() > 0 {
+		logger.WithField("stderr", strings.TrimSpace(stderr.String())).Debug("plugin wrote to stderr")
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("gave up waiting for plugin %s to %s: %s", a.plugin.Name, request.Command, ctx.Err())
+	}
+
+	result := PluginResult{}
+	if output := bytes.TrimSpace(stdout.Bytes()); len(output) > 0 {
+		if err := json.Unmarshal(output, &result); err != nil && runErr == nil {
+			return fmt.Errorf("plugin %s answered %s with invalid JSON: %s", a.plugin.Name, request.Command, err)
+		}
+	}
+	if result.Message != "" {
+		logger.Info(result.Message)
+	}
+
+	switch {
+	case result.Error != "":
+		return fmt.Errorf("plugin %s failed to %s: %s", a.plugin.Name, request.Command, result.Error)
+	case runErr != nil && stderr.Len() > 0:
+		return fmt.Errorf("plugin %s failed to %s: %s: %s", a.plugin.Name, request.Command, runErr, strings.TrimSpace(stderr.String()))
+	case runErr != nil:
+		return fmt.Errorf("plugin %s failed to %s: %s", a.plugin.Name, request.Command, runErr)
+	}
+	return nil
+}
+
+var _ PodAction = &pluginAction{}
+var _ NodeAction = &pluginAction{}
+var _ PodReverter = &pluginAction{}
+var _ NodeReverter = &pluginAction{}
@@ -0,0 +1,137 @@
+package action_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neo-technology/marmoset/chaoskube/action"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	k8smeta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// writePlugin writes a plugin running the given shell script for apply, which records each request
+// it reads as <command>.json in its directory
+func writePlugin(t *testing.T, apply string) (string, func()) {
+	dir, err := ioutil.TempDir("", "plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := `#!/bin/sh
+cat > "` + dir + `/$1.json"
+if [ "$1" = apply ]; then
+` + apply + `
+fi
+`
+	path := filepath.Join(dir, "plugin")
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+// pluginRequest returns the request a plugin got for a command, or nil if it got none
+func pluginRequest(t *testing.T, path, command string) *action.PluginRequest {
+	data, err := ioutil.ReadFile(filepath.Join(filepath.Dir(path), command+".json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := &action.PluginRequest{}
+	if err := json.Unmarshal(data, request); err != nil {
+		t.Fatalf("plugin got invalid JSON for %s: %s", command, err)
+	}
+	return request
+}
+
+func pluginLogger() log.FieldLogger {
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	return logger
+}
+
+func TestParsePlugin(t *testing.T) {
+	plugin, err := action.ParsePlugin("reboot-vm=node:/opt/plugins/reboot-vm")
+	if err != nil {
+		t.Fatalf("ParsePlugin failed with: %s", err)
+	}
+	expected := action.Plugin{Name: "reboot-vm", Kind: action.PluginKindNode, Path: "/opt/plugins/reboot-vm"}
+	if plugin != expected {
+		t.Errorf("Expected %v, actual: %v", expected, plugin)
+	}
+
+	for _, invalid := range []string{"reboot-vm", "=node:/bin/true", "reboot-vm=/bin/true", "reboot-vm=vm:/bin/true", "reboot-vm=pod:"} {
+		if _, err := action.ParsePlugin(invalid); err == nil {
+			t.Errorf("Expected %s to be invalid", invalid)
+		}
+	}
+}
+
+func TestPluginApplyToPod(t *testing.T) {
+	path, cleanup := writePlugin(t, `echo '{"message": "stopped neo4j"}'`)
+	defer cleanup()
+	act := action.NewPluginPodAction(action.Plugin{Name: "stop-neo4j", Kind: action.PluginKindPod, Path: path}, pluginLogger())
+
+	// a plugin with nothing to do on init just exits
+	if err := act.Init(context.Background(), nil); err != nil {
+		t.Fatalf("Init failed with: %s", err)
+	}
+	if request := pluginRequest(t, path, action.PluginInit); request == nil || request.Action != "stop-neo4j" || request.Pod != nil {
+		t.Errorf("Expected an init request without a victim, actual: %v", request)
+	}
+
+	pod := v1.Pod{ObjectMeta: k8smeta.ObjectMeta{Namespace: "graph", Name: "neo4j-0"}}
+	if err := act.ApplyToPod(context.Background(), pod); err != nil {
+		t.Fatalf("ApplyToPod failed with: %s", err)
+	}
+	request := pluginRequest(t, path, action.PluginApply)
+	if request == nil || request.Command != action.PluginApply || request.Pod == nil || request.Pod.Name != "neo4j-0" {
+		t.Errorf("Expected an apply request for neo4j-0, actual: %v", request)
+	}
+	if request := pluginRequest(t, path, action.PluginRevert); request != nil {
+		t.Errorf("Expected no revert after a successful apply, actual: %v", request)
+	}
+}
+
+func TestPluginFailureIsReverted(t *testing.T) {
+	node := &v1.Node{ObjectMeta: k8smeta.ObjectMeta{Name: "vm-1"}}
+
+	for _, tt := range []struct {
+		apply    string
+		timeout  time.Duration
+		expected string
+	}{
+		{`echo '{"error": "no such vm"}'`, time.Minute, "plugin reboot-vm failed to apply: no such vm"},
+		{`echo boom >&2; exit 3`, time.Minute, "exit status 3: boom"},
+		{`echo not json`, time.Minute, "invalid JSON"},
+		{`exec sleep 10`, 100 * time.Millisecond, "gave up waiting for plugin reboot-vm to apply"},
+		// the shell waits for its child, which holds on to stdout
+		{`sleep 10`, 100 * time.Millisecond, "gave up waiting for plugin reboot-vm to apply"},
+	} {
+		path, cleanup := writePlugin(t, tt.apply)
+		act := action.NewPluginNodeAction(action.Plugin{Name: "reboot-vm", Kind: action.PluginKindNode, Path: path}, pluginLogger())
+
+		ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+		start := time.Now()
+		err := act.ApplyToNode(ctx, nil, node)
+		cancel()
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("Expected error containing '%s', actual: %v", tt.expected, err)
+		}
+		if elapsed := time.Since(start); elapsed > tt.timeout+5*time.Second {
+			t.Errorf("Expected '%s' to be given up on after %s, took %s", tt.apply, tt.timeout, elapsed)
+		}
+		if request := pluginRequest(t, path, action.PluginRevert); request == nil || request.Node == nil || request.Node.Name != "vm-1" {
+			t.Errorf("Expected vm-1 to be reverted after '%s', actual: %v", tt.apply, request)
+		}
+		cleanup()
+	}
+}
@@ -1,6 +1,8 @@
 package action
 
 import (
+	"context"
+
 	"k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -14,10 +16,13 @@ type deletePod struct {
 	client kubernetes.Interface
 }
 
-func (s *deletePod) Init(k8sclient kubernetes.Interface) error {
+func (s *deletePod) Init(ctx context.Context, k8sclient kubernetes.Interface) error {
 	return nil
 }
-func (s *deletePod) ApplyToPod(victim v1.Pod) error {
+func (s *deletePod) ApplyToPod(ctx context.Context, victim v1.Pod) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	return s.client.CoreV1().Pods(victim.Namespace).Delete(victim.Name, nil)
 }
 func (s *deletePod) Name() string { return "delete pod" }
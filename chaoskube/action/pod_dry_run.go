@@ -1,6 +1,8 @@
 package action
 
 import (
+	"context"
+
 	"k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -13,10 +15,10 @@ func NewDryRunPodAction() PodAction {
 type podDryRun struct {
 }
 
-func (s *podDryRun) Init(k8sclient kubernetes.Interface) error {
+func (s *podDryRun) Init(ctx context.Context, k8sclient kubernetes.Interface) error {
 	return nil
 }
-func (s *podDryRun) ApplyToPod(victim v1.Pod) error {
+func (s *podDryRun) ApplyToPod(ctx context.Context, victim v1.Pod) error {
 	return nil
 }
 func (s *podDryRun) Name() string { return "dry run" }
@@ -1,15 +1,24 @@
 package action
 
 import (
+	"context"
 	"fmt"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+	"net/http"
 	"os"
+	"sync"
+	"time"
 )
 
+// how long to wait for a command stream to end once its connection is closed
+const streamCloseTimeout = 10 * time.Second
+
 func NewExecAction(client restclient.Interface, config *restclient.Config, containerName string, command []string) PodAction {
 	return &execOnPod{client, config, containerName, command}
 }
@@ -23,12 +32,16 @@ type execOnPod struct {
 	command       []string
 }
 
-func (s *execOnPod) Init(k8sclient kubernetes.Interface) error {
+func (s *execOnPod) Init(ctx context.Context, k8sclient kubernetes.Interface) error {
 	return nil
 }
 
 // Based on https://github.com/kubernetes/kubernetes/blob/master/pkg/kubectl/cmd/exec.go
-func (s *execOnPod) ApplyToPod(pod v1.Pod) error {
+func (s *execOnPod) ApplyToPod(ctx context.Context, pod v1.Pod) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	var container string
 	if s.containerName == "" {
 		for _, c := range pod.Spec.Containers {
@@ -54,21 +67,76 @@ func (s *execOnPod) ApplyToPod(pod v1.Pod) error {
 		TTY:       false,
 	}, scheme.ParameterCodec)
 
-	exec, err := remotecommand.NewSPDYExecutor(s.config, "POST", req.URL())
+	transport, upgrader, err := spdy.RoundTripperFor(s.config)
+	if err != nil {
+		return err
+	}
+	// Stream takes no context, so it is ended by closing the connection it streams over
+	conn := &closableUpgrader{Upgrader: upgrader}
+	exec, err := remotecommand.NewSPDYExecutorForTransports(transport, conn, "POST", req.URL())
 	if err != nil {
 		return err
 	}
 	// TODO: Collect stderr/stdout in RAM and log
-	err = exec.Stream(remotecommand.StreamOptions{
-		Stdin:             nil,
-		Stdout:            os.Stdout,
-		Stderr:            os.Stderr,
-		Tty:               false,
-		TerminalSizeQueue: nil,
-	})
-
-	return err
+	done := make(chan error, 1)
+	go func() {
+		done <- exec.Stream(remotecommand.StreamOptions{
+			Stdin:             nil,
+			Stdout:            os.Stdout,
+			Stderr:            os.Stderr,
+			Tty:               false,
+			TerminalSizeQueue: nil,
+		})
+	}()
+
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		// the command may still finish in the pod, but nothing waits for it here any more
+		conn.Close()
+		select {
+		case <-done:
+		case <-time.After(streamCloseTimeout):
+		}
+		return fmt.Errorf("gave up waiting for command in %s/%s: %s", pod.Namespace, pod.Name, ctx.Err())
+	}
 }
 func (s *execOnPod) Name() string { return fmt.Sprintf("exec '%v'", s.command) }
 
+// closableUpgrader remembers the connection it upgrades to, so it can be closed from outside
+type closableUpgrader struct {
+	spdy.Upgrader
+
+	mutex  sync.Mutex
+	conn   httpstream.Connection
+	closed bool
+}
+
+func (u *closableUpgrader) NewConnection(resp *http.Response) (httpstream.Connection, error) {
+	conn, err := u.Upgrader.NewConnection(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.closed {
+		conn.Close()
+		return nil, fmt.Errorf("connection closed while upgrading")
+	}
+	u.conn = conn
+	return conn, nil
+}
+
+// Close closes the connection, or the one yet to be upgraded to
+func (u *closableUpgrader) Close() {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.closed = true
+	if u.conn != nil {
+		u.conn.Close()
+	}
+}
+
 var _ PodAction = &execOnPod{}
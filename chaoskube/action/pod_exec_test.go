@@ -0,0 +1,64 @@
+package action_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neo-technology/marmoset/chaoskube/action"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	"k8s.io/apimachinery/pkg/util/remotecommand"
+	"k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
+)
+
+// hangingExecServer accepts exec streams, but never answers on them; closed is closed once the
+// client closes its connection
+func hangingExecServer(closed chan struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(httpstream.HeaderProtocolVersion, remotecommand.StreamProtocolV4Name)
+		conn := spdy.NewResponseUpgrader().UpgradeResponse(w, r, func(stream httpstream.Stream, replySent <-chan struct{}) error {
+			return nil
+		})
+		if conn == nil {
+			return
+		}
+		<-conn.CloseChan()
+		close(closed)
+	}))
+}
+
+func TestExecActionCancelled(t *testing.T) {
+	closed := make(chan struct{})
+	server := hangingExecServer(closed)
+	defer server.Close()
+
+	config := &restclient.Config{Host: server.URL}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	act := action.NewExecAction(client.CoreV1().RESTClient(), config, "", []string{"sleep", "60"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = act.ApplyToPod(ctx, *newPodOnNode("p1", "test-node"))
+	if err == nil || !strings.Contains(err.Error(), "gave up waiting for command in default/p1") {
+		t.Errorf("Expected the command to be given up on, actual: %v", err)
+	}
+	// the stream has returned by then, rather than being left behind
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the stream to end once cancelled, took %s", elapsed)
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Expected the connection to be closed")
+	}
+}
@@ -1,24 +1,44 @@
 package action
 
 import (
+	"context"
+	"time"
+
 	"k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
+// Actions honour the cancellation and deadline of the context they are given: they stop waiting,
+// don't start any further API calls and undo what they can, like uncordoning a drained node.
+
 type NodeAction interface {
 	// Called once at startup, do any initial setup here
-	Init(k8sclient kubernetes.Interface) error
+	Init(ctx context.Context, k8sclient kubernetes.Interface) error
 	// Imbue chaos in the given victim
-	ApplyToNode(client kubernetes.Interface, victim *v1.Node) error
+	ApplyToNode(ctx context.Context, client kubernetes.Interface, victim *v1.Node) error
 	// Name of this action, ideally a verb - like "terminate pod"
 	Name() string
 }
 
 type PodAction interface {
 	// Called once at startup, do any initial setup here
-	Init(k8sclient kubernetes.Interface) error
+	Init(ctx context.Context, k8sclient kubernetes.Interface) error
 	// Imbue chaos in the given victim
-	ApplyToPod(victim v1.Pod) error
+	ApplyToPod(ctx context.Context, victim v1.Pod) error
 	// Name of this action, ideally a verb - like "terminate pod"
 	Name() string
 }
+
+// how long undoing what an action did may take; it isn't bound to the context of the action, whose
+// deadline may be what failed it
+const revertTimeout = time.Minute
+
+// PodReverter is implemented by PodActions that can undo what they did to a pod
+type PodReverter interface {
+	RevertPod(ctx context.Context, victim v1.Pod) error
+}
+
+// NodeReverter is implemented by NodeActions that can undo what they did to a node
+type NodeReverter interface {
+	RevertNode(ctx context.Context, client kubernetes.Interface, victim *v1.Node) error
+}
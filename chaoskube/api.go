@@ -0,0 +1,237 @@
+package chaoskube
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/neo-technology/marmoset/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// API serves a JSON API to control and inspect a Chaoskube over HTTP. Every request must carry
+// the token in an "Authorization: Bearer <token>" header.
+type API struct {
+	Chaoskube *Chaoskube
+	// tells when the next run is scheduled; optional
+	Scheduler *Scheduler
+	// the bearer token clients must present
+	Token string
+	// an instance of logrus.StdLogger to write log messages to
+	Logger log.FieldLogger
+}
+
+// Status is the response of GET /status
+type Status struct {
+	// who is targeted and what is done to them
+	Spec ChaosSpec `json:"spec"`
+	// the rules deciding when chaos is suspended
+	Rules Rules `json:"rules"`
+	// whether chaos is suspended right now
+	Excluded bool `json:"excluded"`
+	// why chaos is suspended right now, if it is
+	Exclusion *Exclusion `json:"exclusion,omitempty"`
+	// why it can't be told whether chaos is suspended, which suspends it as well
+	ExclusionError string `json:"exclusionError,omitempty"`
+	// when the schedule fires next, if known
+	NextRun *time.Time `json:"nextRun,omitempty"`
+	// the outcomes of the most recent runs, oldest first
+	Outcomes []Outcome `json:"outcomes"`
+}
+
+// Rules lists the configured exclusions and allowed windows
+type Rules struct {
+	ExcludedWeekdays   []string          `json:"excludedWeekdays"`
+	ExcludedTimesOfDay []util.TimePeriod `json:"excludedTimesOfDay"`
+	ExcludedDaysOfYear []string          `json:"excludedDaysOfYear"`
+	ExcludedDates      []util.DateRange  `json:"excludedDates"`
+	Calendars          []string          `json:"calendars"`
+	AllowedWindows     []util.Window     `json:"allowedWindows"`
+	KillSwitch         *KillSwitch       `json:"killSwitch,omitempty"`
+	HealthGate         *HealthGate       `json:"healthGate,omitempty"`
+	Timezone           string            `json:"timezone"`
+}
+
+// NewAPI returns an API controlling the given Chaoskube
+func NewAPI(chaoskube *Chaoskube, scheduler *Scheduler, token string, logger log.FieldLogger) *API {
+	return &API{
+		Chaoskube: chaoskube,
+		Scheduler: scheduler,
+		Token:     token,
+		Logger:    logger,
+	}
+}
+
+// Register adds the endpoints of the API to the given ServeMux
+func (a *API) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/pause", a.authenticated(http.MethodPost, a.pause))
+	mux.HandleFunc("/resume", a.authenticated(http.MethodPost, a.resume))
+	mux.HandleFunc("/trigger", a.authenticated(http.MethodPost, a.trigger))
+	mux.HandleFunc("/status", a.authenticated(http.MethodGet, a.status))
+	mux.HandleFunc("/candidates", a.authenticated(http.MethodGet, a.candidates))
+	mux.HandleFunc("/history", a.authenticated(http.MethodGet, a.history))
+}
+
+// authenticated only passes requests with the given method and a valid bearer token to handler
+func (a *API) authenticated(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(authorization, "Bearer ")
+		if a.Token == "" || token == authorization || subtle.ConstantTimeCompare([]byte(token), []byte(a.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid bearer token"))
+			return
+		}
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// pause handles POST /pause?duration=1h&reason=...; without a duration chaos is paused until resumed
+func (a *API) pause(w http.ResponseWriter, r *http.Request) {
+	var duration time.Duration
+	if value := r.URL.Query().Get("duration"); value != "" {
+		var err error
+		if duration, err = time.ParseDuration(value); err != nil || duration < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid duration '%s'", value))
+			return
+		}
+	}
+
+	pause := a.Chaoskube.Pause(duration, r.URL.Query().Get("reason"))
+
+	response := map[string]interface{}{"paused": true, "reason": pause.Reason}
+	fields := log.Fields{"reason": pause.Reason}
+	if !pause.Until.IsZero() {
+		response["until"] = pause.Until.Format(time.RFC3339)
+		fields["until"] = pause.Until.Format(time.RFC3339)
+	}
+	a.Logger.WithFields(fields).Info("chaos paused through API")
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// resume handles POST /resume. It only lifts a pause made through the API, not the kill switch.
+func (a *API) resume(w http.ResponseWriter, r *http.Request) {
+	a.Chaoskube.Resume()
+	a.Logger.Info("chaos resumed through API")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"paused": false})
+}
+
+// trigger handles POST /trigger, running once right away. Exclusions still apply. The run is
+// cancelled if the client goes away.
+func (a *API) trigger(w http.ResponseWriter, r *http.Request) {
+	a.Logger.Info("run triggered through API")
+
+	outcome, err := a.Chaoskube.RunOnce(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, outcome)
+		return
+	}
+	writeJSON(w, http.StatusOK, outcome)
+}
+
+// status handles GET /status
+func (a *API) status(w http.ResponseWriter, r *http.Request) {
+	c := a.Chaoskube
+
+	status := Status{
+		Spec:     c.Spec,
+		Rules:    c.Rules(),
+		Outcomes: c.Outcomes(),
+	}
+
+	exclusion, err := c.Exclusion(c.Now().In(c.Timezone))
+	status.Excluded = exclusion != nil || err != nil
+	status.Exclusion = exclusion
+	if err != nil {
+		status.ExclusionError = err.Error()
+	}
+
+	if a.Scheduler != nil {
+		if next := a.Scheduler.NextRun(); !next.IsZero() {
+			status.NextRun = &next
+		}
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// candidates handles GET /candidates, listing what the spec would currently pick from
+func (a *API) candidates(w http.ResponseWriter, r *http.Request) {
+	c := a.Chaoskube
+
+	report, err := c.Spec.Candidates(c.Client, c.Now())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// history handles GET /history?from=24h&to=...&namespace=...&action=...
+func (a *API) history(w http.ResponseWriter, r *http.Request) {
+	c := a.Chaoskube
+	if c.History == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no history is kept"))
+		return
+	}
+
+	params := r.URL.Query()
+	query, err := ParseHistoryQuery(params.Get("from"), params.Get("to"), params.Get("namespace"), params.Get("action"), c.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	records, err := c.History.Query(query)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+// Rules returns the configured exclusions and allowed windows
+func (c *Chaoskube) Rules() Rules {
+	rules := Rules{
+		ExcludedWeekdays:   []string{},
+		ExcludedTimesOfDay: c.ExcludedTimesOfDay,
+		ExcludedDaysOfYear: []string{},
+		ExcludedDates:      c.ExcludedDateRanges,
+		Calendars:          []string{},
+		AllowedWindows:     c.AllowedWindows,
+		KillSwitch:         c.KillSwitch,
+		HealthGate:         c.HealthGate,
+		Timezone:           c.Timezone.String(),
+	}
+	for _, wd := range c.ExcludedWeekdays {
+		rules.ExcludedWeekdays = append(rules.ExcludedWeekdays, wd.String())
+	}
+	for _, d := range c.ExcludedDaysOfYear {
+		rules.ExcludedDaysOfYear = append(rules.ExcludedDaysOfYear, d.Format(util.YearDay))
+	}
+	for _, calendar := range c.Calendars {
+		rules.Calendars = append(rules.Calendars, calendar.String())
+	}
+	return rules
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
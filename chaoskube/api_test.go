@@ -0,0 +1,158 @@
+package chaoskube
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/neo-technology/marmoset/util"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const testToken = "s3cr3t"
+
+func (suite *Suite) TestAPIRequiresToken() {
+	api := NewAPI(suite.setupOnFriday(), nil, testToken, logger)
+
+	for _, header := range []string{"", "Bearer", "Bearer wrong", testToken} {
+		response := suite.request(api, http.MethodGet, "/status", header)
+		suite.Equal(http.StatusUnauthorized, response.Code, header)
+	}
+
+	// without a token configured, nobody gets in
+	api.Token = ""
+	response := suite.request(api, http.MethodGet, "/status", "Bearer ")
+	suite.Equal(http.StatusUnauthorized, response.Code)
+}
+
+func (suite *Suite) TestAPIChecksMethod() {
+	api := NewAPI(suite.setupOnFriday(), nil, testToken, logger)
+
+	response := suite.request(api, http.MethodGet, "/pause", "Bearer "+testToken)
+	suite.Equal(http.StatusMethodNotAllowed, response.Code)
+	suite.Equal(http.MethodPost, response.Header().Get("Allow"))
+}
+
+func (suite *Suite) TestAPIPauseAndResume() {
+	chaoskube := suite.setupOnFriday()
+	recorder := &chaosRecorder{}
+	chaoskube.Spec = recorder
+	api := NewAPI(chaoskube, nil, testToken, logger)
+
+	response := suite.request(api, http.MethodPost, "/pause?duration=1h&reason=incident", "Bearer "+testToken)
+	suite.Require().Equal(http.StatusOK, response.Code)
+	body := map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal(response.Body.Bytes(), &body))
+	suite.Equal(true, body["paused"])
+	suite.Equal("incident", body["reason"])
+	suite.Equal("1869-09-24T16:04:05Z", body["until"])
+	// the gauge tells right away, not on the next run
+	suite.Equal(1.0, pausedValue())
+
+	suite.Require().NoError(chaoskube.TerminateVictim(context.Background()))
+	suite.False(recorder.invoked)
+
+	response = suite.request(api, http.MethodPost, "/resume", "Bearer "+testToken)
+	suite.Require().Equal(http.StatusOK, response.Code)
+	suite.Equal(0.0, pausedValue())
+
+	suite.Require().NoError(chaoskube.TerminateVictim(context.Background()))
+	suite.True(recorder.invoked)
+
+	response = suite.request(api, http.MethodPost, "/pause?duration=soon", "Bearer "+testToken)
+	suite.Equal(http.StatusBadRequest, response.Code)
+}
+
+func (suite *Suite) TestAPITrigger() {
+	chaoskube := suite.setupWithPods(
+		labels.Everything(),
+		labels.Everything(),
+		labels.Everything(),
+		[]time.Weekday{},
+		[]util.TimePeriod{},
+		[]time.Time{},
+		time.UTC,
+		time.Duration(0),
+		true,
+	)
+	api := NewAPI(chaoskube, nil, testToken, logger)
+
+	response := suite.request(api, http.MethodPost, "/trigger", "Bearer "+testToken)
+	suite.Require().Equal(http.StatusOK, response.Code)
+
+	outcome := Outcome{}
+	suite.Require().NoError(json.Unmarshal(response.Body.Bytes(), &outcome))
+	suite.Require().Len(outcome.Victims, 1)
+	suite.Equal(KindPod, outcome.Victims[0].Kind)
+	suite.Contains([]string{"foo", "bar"}, outcome.Victims[0].Name)
+}
+
+func (suite *Suite) TestAPIStatus() {
+	chaoskube := suite.setupOnFriday()
+	chaoskube.Spec = &chaosRecorder{}
+	chaoskube.ExcludedWeekdays = []time.Weekday{time.Friday}
+	scheduler := NewScheduler(Interval(10*time.Minute), time.UTC, logger)
+	scheduler.setNextRun(ThankGodItsFriday{}.Now().Add(10 * time.Minute))
+	api := NewAPI(chaoskube, scheduler, testToken, logger)
+
+	chaoskube.TerminateVictim(context.Background())
+
+	response := suite.request(api, http.MethodGet, "/status", "Bearer "+testToken)
+	suite.Require().Equal(http.StatusOK, response.Code)
+
+	status := struct {
+		Rules     Rules
+		Excluded  bool
+		Exclusion *Exclusion
+		NextRun   time.Time
+		Outcomes  []Outcome
+	}{}
+	suite.Require().NoError(json.Unmarshal(response.Body.Bytes(), &status))
+	suite.Equal([]string{"Friday"}, status.Rules.ExcludedWeekdays)
+	suite.Equal("UTC", status.Rules.Timezone)
+	suite.True(status.Excluded)
+	suite.Require().NotNil(status.Exclusion)
+	suite.Equal(msgWeekdayExcluded, status.Exclusion.Reason)
+	suite.Equal(ThankGodItsFriday{}.Now().Add(10*time.Minute), status.NextRun)
+	suite.Len(status.Outcomes, 1)
+}
+
+func (suite *Suite) TestAPICandidates() {
+	chaoskube := suite.setupWithPods(
+		labels.Everything(),
+		labels.Everything(),
+		labels.Everything(),
+		[]time.Weekday{},
+		[]util.TimePeriod{},
+		[]time.Time{},
+		time.UTC,
+		time.Duration(0),
+		false,
+	)
+	api := NewAPI(chaoskube, nil, testToken, logger)
+
+	response := suite.request(api, http.MethodGet, "/candidates", "Bearer "+testToken)
+	suite.Require().Equal(http.StatusOK, response.Code)
+
+	report := CandidateReport{}
+	suite.Require().NoError(json.Unmarshal(response.Body.Bytes(), &report))
+	suite.Equal(3, report.Listed)
+	suite.Len(report.Candidates, 2)
+	suite.Contains(report.Filters, FilterCount{Filter: FilterPhase, Removed: 1})
+}
+
+func (suite *Suite) request(api *API, method, target, authorization string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	api.Register(mux)
+
+	request := httptest.NewRequest(method, target, nil)
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+	response := httptest.NewRecorder()
+	mux.ServeHTTP(response, request)
+	return response
+}
@@ -0,0 +1,211 @@
+package chaoskube
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+const (
+	// What a Limit counts victims by
+	ScopeTotal     = "total"
+	ScopeNamespace = "namespace"
+	ScopeOwner     = "owner"
+	ScopeNode      = "node"
+	ScopeUID       = "uid"
+)
+
+// Limit caps how many victims there may be within a sliding window, in total or per target. For
+// example, at most 1 per owner in 30 minutes is Limit{Scope: ScopeOwner, Max: 1, Window: 30 * time.Minute}.
+type Limit struct {
+	// one of total, namespace, owner, node or uid
+	Scope  string        `json:"scope"`
+	Max    int           `json:"max"`
+	Window time.Duration `json:"window"`
+}
+
+// ParseLimit parses a limit written as <scope>:<max>/<window>, e.g. "total:3/1h" or "uid:1/24h"
+func ParseLimit(limit string) (Limit, error) {
+	parts := strings.SplitN(strings.TrimSpace(limit), ":", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("Invalid limit '%v': must be <scope>:<max>/<window>", limit)
+	}
+
+	switch parts[0] {
+	case ScopeTotal, ScopeNamespace, ScopeOwner, ScopeNode, ScopeUID:
+	default:
+		return Limit{}, fmt.Errorf("Invalid limit '%v': scope must be total, namespace, owner, node or uid", limit)
+	}
+
+	rate := strings.SplitN(parts[1], "/", 2)
+	if len(rate) != 2 {
+		return Limit{}, fmt.Errorf("Invalid limit '%v': must be <scope>:<max>/<window>", limit)
+	}
+	max, err := strconv.Atoi(rate[0])
+	if err != nil || max < 0 {
+		return Limit{}, fmt.Errorf("Invalid limit '%v': invalid maximum '%v'", limit, rate[0])
+	}
+	window, err := time.ParseDuration(rate[1])
+	if err != nil || window <= 0 {
+		return Limit{}, fmt.Errorf("Invalid limit '%v': invalid window '%v'", limit, rate[1])
+	}
+
+	return Limit{Scope: parts[0], Max: max, Window: window}, nil
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%s:%d/%s", l.Scope, l.Max, l.Window)
+}
+
+// key returns what the limit counts a victim by; victims with the same key count together
+func (l Limit) key(victim *Victim) string {
+	switch l.Scope {
+	case ScopeNamespace:
+		return victim.Namespace
+	case ScopeOwner:
+		// a pod without a controller is its own owner
+		if victim.Owner != "" {
+			return victim.Namespace + "/" + victim.Owner
+		}
+		return victimID(victim)
+	case ScopeNode:
+		return victim.Node
+	case ScopeUID:
+		return victimID(victim)
+	}
+	return ""
+}
+
+// victimID identifies a victim by its UID, or by its name if it has none
+func victimID(victim *Victim) string {
+	if victim.UID != "" {
+		return string(victim.UID)
+	}
+	return victim.Kind + "/" + victim.Namespace + "/" + victim.Name
+}
+
+// BlastRadius enforces limits on how much chaos there is. It remembers the victims of the longest
+// window in memory; given a History, it picks up where it left off after a restart.
+type BlastRadius struct {
+	Limits []Limit
+	// remember victims at least this long, for Count beyond the windows of the limits
+	Retention time.Duration
+	// where past victims are read from on Init; optional
+	History History
+
+	// guards strikes
+	mutex sync.Mutex
+	// the victims within the longest window, oldest first
+	strikes []strike
+}
+
+type strike struct {
+	time   time.Time
+	victim *Victim
+}
+
+// Init remembers the victims of recent runs from the History
+func (b *BlastRadius) Init(now time.Time) error {
+	if b.History == nil {
+		return nil
+	}
+
+	records, err := b.History.Query(HistoryQuery{From: now.Add(-b.longestWindow())})
+	if err != nil {
+		return fmt.Errorf("failed to read past victims from history: %s", err)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.strikes = []strike{}
+	for _, record := range records {
+		// chaos was attempted on failed victims too, so they count as well
+		if record.Victim != nil && (record.Outcome == OutcomeApplied || record.Outcome == OutcomeFailed) {
+			b.strikes = append(b.strikes, strike{time: record.Time, victim: record.Victim})
+		}
+	}
+	return nil
+}
+
+// Allows tells whether chaos may be imbued in the victim without exceeding a limit
+func (b *BlastRadius) Allows(victim *Victim, now time.Time) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, limit := range b.Limits {
+		if b.count(limit, victim, now) >= limit.Max {
+			return false
+		}
+	}
+	return true
+}
+
+// Count tells how many victims within the window of the limit count together with the given one
+func (b *BlastRadius) Count(limit Limit, victim *Victim, now time.Time) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.count(limit, victim, now)
+}
+
+func (b *BlastRadius) count(limit Limit, victim *Victim, now time.Time) int {
+	key := limit.key(victim)
+	count := 0
+	for _, s := range b.strikes {
+		if now.Sub(s.time) < limit.Window && limit.key(s.victim) == key {
+			count++
+		}
+	}
+	return count
+}
+
+// Strike remembers that chaos was imbued in the victim
+func (b *BlastRadius) Strike(victim *Victim, now time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.strikes = append(b.strikes, strike{time: now, victim: victim})
+
+	// forget what no limit counts anymore
+	longest := b.longestWindow()
+	for len(b.strikes) > 0 && now.Sub(b.strikes[0].time) >= longest {
+		b.strikes = b.strikes[1:]
+	}
+}
+
+func (b *BlastRadius) longestWindow() time.Duration {
+	longest := b.Retention
+	for _, limit := range b.Limits {
+		if limit.Window > longest {
+			longest = limit.Window
+		}
+	}
+	return longest
+}
+
+// filterPods removes the pods the limits don't allow chaos in
+func (b *BlastRadius) filterPods(pods []v1.Pod, action string, now time.Time) []v1.Pod {
+	filteredList := []v1.Pod{}
+	for _, pod := range pods {
+		if b.Allows(podVictim(pod, action), now) {
+			filteredList = append(filteredList, pod)
+		}
+	}
+	return filteredList
+}
+
+// filterNodes removes the nodes the limits don't allow chaos in
+func (b *BlastRadius) filterNodes(nodes []v1.Node, action string, now time.Time) []v1.Node {
+	filteredList := []v1.Node{}
+	for _, node := range nodes {
+		if b.Allows(nodeVictim(node, action), now) {
+			filteredList = append(filteredList, node)
+		}
+	}
+	return filteredList
+}
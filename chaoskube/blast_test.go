@@ -0,0 +1,111 @@
+package chaoskube
+
+import (
+	"context"
+	"time"
+
+	"github.com/neo-technology/marmoset/util"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func (suite *Suite) TestParseLimit() {
+	limit, err := ParseLimit("owner:1/30m")
+	suite.Require().NoError(err)
+	suite.Equal(Limit{Scope: ScopeOwner, Max: 1, Window: 30 * time.Minute}, limit)
+	suite.Equal("owner:1/30m0s", limit.String())
+
+	for _, invalid := range []string{"", "total", "total:3", "team:3/1h", "total:many/1h", "total:-1/1h", "total:3/soon", "total:3/0s"} {
+		_, err := ParseLimit(invalid)
+		suite.Error(err, invalid)
+	}
+}
+
+func (suite *Suite) TestBlastRadius() {
+	friday := ThankGodItsFriday{}.Now()
+	foo := &Victim{Kind: KindPod, Namespace: "default", Name: "foo", UID: "1", Owner: "ReplicaSet/app", Node: "node1"}
+	bar := &Victim{Kind: KindPod, Namespace: "default", Name: "bar", UID: "2", Owner: "ReplicaSet/app", Node: "node2"}
+	baz := &Victim{Kind: KindPod, Namespace: "testing", Name: "baz", UID: "3", Node: "node1"}
+
+	for _, tt := range []struct {
+		limit    string
+		allowed  []*Victim
+		excluded []*Victim
+	}{
+		{"total:1/1h", []*Victim{}, []*Victim{foo, bar, baz}},
+		{"total:2/1h", []*Victim{foo, bar, baz}, []*Victim{}},
+		{"namespace:1/1h", []*Victim{baz}, []*Victim{foo, bar}},
+		{"owner:1/1h", []*Victim{baz}, []*Victim{foo, bar}},
+		{"node:1/1h", []*Victim{bar}, []*Victim{foo, baz}},
+		{"uid:1/1h", []*Victim{bar, baz}, []*Victim{foo}},
+	} {
+		limit, err := ParseLimit(tt.limit)
+		suite.Require().NoError(err)
+		blastRadius := &BlastRadius{Limits: []Limit{limit}}
+		blastRadius.Strike(foo, friday)
+
+		for _, victim := range tt.allowed {
+			suite.True(blastRadius.Allows(victim, friday.Add(time.Minute)), "%s allows %s", tt.limit, victim.Name)
+		}
+		for _, victim := range tt.excluded {
+			suite.False(blastRadius.Allows(victim, friday.Add(time.Minute)), "%s excludes %s", tt.limit, victim.Name)
+			// but only within the window
+			suite.True(blastRadius.Allows(victim, friday.Add(time.Hour)), "%s allows %s later", tt.limit, victim.Name)
+		}
+	}
+}
+
+// TestBlastRadiusFromHistory tests that limits hold across restarts
+func (suite *Suite) TestBlastRadiusFromHistory() {
+	friday := ThankGodItsFriday{}.Now()
+	history := &ConfigMapHistory{Client: fake.NewSimpleClientset(), Namespace: "marmoset", Name: "history", Size: 10}
+	foo := &Victim{Kind: KindPod, Namespace: "default", Name: "foo", UID: "1"}
+
+	suite.Require().NoError(history.Append(Record{Time: friday.Add(-2 * time.Hour), Victim: foo, Outcome: OutcomeApplied}))
+	suite.Require().NoError(history.Append(Record{Time: friday.Add(-30 * time.Minute), Outcome: OutcomeExcluded}))
+
+	blastRadius := &BlastRadius{Limits: []Limit{{Scope: ScopeUID, Max: 1, Window: time.Hour}}, History: history}
+	suite.Require().NoError(blastRadius.Init(friday))
+	suite.True(blastRadius.Allows(foo, friday))
+
+	suite.Require().NoError(history.Append(Record{Time: friday.Add(-10 * time.Minute), Victim: foo, Outcome: OutcomeFailed}))
+	suite.Require().NoError(blastRadius.Init(friday))
+	suite.False(blastRadius.Allows(foo, friday))
+}
+
+// TestPodChaosSpecBlastRadius tests that a spec never picks a pod twice within the limits
+func (suite *Suite) TestPodChaosSpecBlastRadius() {
+	chaoskube := suite.setupWithPods(
+		labels.Everything(),
+		labels.Everything(),
+		labels.Everything(),
+		[]time.Weekday{},
+		[]util.TimePeriod{},
+		[]time.Time{},
+		time.UTC,
+		time.Duration(0),
+		true,
+	)
+	chaoskube.Now = ThankGodItsFriday{}.Now
+	spec := chaoskube.Spec.(*PodChaosSpec)
+	spec.BlastRadius = &BlastRadius{Limits: []Limit{{Scope: ScopeUID, Max: 1, Window: 24 * time.Hour}}}
+
+	victims := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		outcome, err := chaoskube.RunOnce(context.Background())
+		suite.Require().NoError(err)
+		suite.Require().Len(outcome.Victims, 1)
+		victims[outcome.Victims[0].Name] = true
+	}
+	suite.Len(victims, 2)
+
+	outcome, err := chaoskube.RunOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.Empty(outcome.Victims)
+
+	report, err := spec.Candidates(chaoskube.Client, chaoskube.Now())
+	suite.Require().NoError(err)
+	suite.Empty(report.Candidates)
+	suite.Equal(FilterCount{Filter: FilterBlastRadius, Removed: 2}, report.Filters[len(report.Filters)-1])
+}
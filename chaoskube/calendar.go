@@ -0,0 +1,101 @@
+package chaoskube
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/neo-technology/marmoset/util"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Calendar is a source of excluded days, such as company holidays and change freezes. It is read
+// before every run, so changes take effect without restarting.
+type Calendar interface {
+	// Ranges returns the days currently listed in the calendar
+	Ranges(client kubernetes.Interface) ([]util.DateRange, error)
+	// Human-readable description of where the calendar comes from
+	String() string
+}
+
+// FileCalendar reads an iCalendar (.ics) file from disk, e.g. one mounted from a ConfigMap
+type FileCalendar struct {
+	Path string
+}
+
+func (c *FileCalendar) Ranges(client kubernetes.Interface) ([]util.DateRange, error) {
+	file, err := os.Open(c.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return util.ParseICalendar(file)
+}
+
+func (c *FileCalendar) String() string {
+	return c.Path
+}
+
+// ConfigMapCalendar reads iCalendar documents from a ConfigMap through the API. If Key is empty,
+// every entry of the ConfigMap is read.
+type ConfigMapCalendar struct {
+	Namespace string
+	Name      string
+	Key       string
+}
+
+func (c *ConfigMapCalendar) Ranges(client kubernetes.Interface) ([]util.DateRange, error) {
+	configMap, err := client.CoreV1().ConfigMaps(c.Namespace).Get(c.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{c.Key}
+	if c.Key == "" {
+		keys = make([]string, 0, len(configMap.Data))
+		for key := range configMap.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+	}
+
+	ranges := []util.DateRange{}
+	for _, key := range keys {
+		data, ok := configMap.Data[key]
+		if !ok {
+			return nil, fmt.Errorf("configmap %s has no key '%s'", c, key)
+		}
+		parsed, err := util.ParseICalendar(strings.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("configmap %s, key '%s': %s", c, key, err)
+		}
+		ranges = append(ranges, parsed...)
+	}
+	return ranges, nil
+}
+
+func (c *ConfigMapCalendar) String() string {
+	if c.Key == "" {
+		return fmt.Sprintf("%s/%s", c.Namespace, c.Name)
+	}
+	return fmt.Sprintf("%s/%s:%s", c.Namespace, c.Name, c.Key)
+}
+
+// ParseConfigMapCalendar parses a reference to a ConfigMap calendar, written as namespace/name or
+// namespace/name:key
+func ParseConfigMapCalendar(ref string) (*ConfigMapCalendar, error) {
+	parts := strings.SplitN(strings.TrimSpace(ref), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("Invalid configmap reference '%v': must be namespace/name[:key]", ref)
+	}
+
+	calendar := &ConfigMapCalendar{Namespace: parts[0], Name: parts[1]}
+	if i := strings.Index(calendar.Name, ":"); i >= 0 {
+		calendar.Name, calendar.Key = calendar.Name[:i], calendar.Name[i+1:]
+	}
+	return calendar, nil
+}
@@ -0,0 +1,118 @@
+package chaoskube
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+
+	"github.com/neo-technology/marmoset/util"
+
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const blackFridayCalendar = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:18690924\r\n" +
+	"SUMMARY:Black Friday\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+// TestTerminateVictimDateRanges tests that chaos is suspended on excluded date ranges
+func (suite *Suite) TestTerminateVictimDateRanges() {
+	for _, tt := range []struct {
+		excludedDateRanges string
+		expectSpecInvoked  bool
+	}{
+		{"", true},
+		{"Sep20-Sep30", false},
+		{"Sep25-Sep30", true},
+		{"Sep20-Jan3", false},
+		{"1869-09-24", false},
+		{"1870-09-24", true},
+		{"1869-09-01/1869-10-01", false},
+	} {
+		ranges, err := util.ParseDateRanges(tt.excludedDateRanges)
+		suite.Require().NoError(err)
+
+		chaoskube := suite.setupOnFriday()
+		recorder := &chaosRecorder{}
+		chaoskube.Spec = recorder
+		chaoskube.ExcludedDateRanges = ranges
+
+		err = chaoskube.TerminateVictim(context.Background())
+		suite.Require().NoError(err)
+
+		suite.Equal(tt.expectSpecInvoked, recorder.invoked, tt.excludedDateRanges)
+	}
+}
+
+// TestTerminateVictimConfigMapCalendar tests that calendars are read from ConfigMaps on every run
+func (suite *Suite) TestTerminateVictimConfigMapCalendar() {
+	chaoskube := suite.setupOnFriday()
+	recorder := &chaosRecorder{}
+	chaoskube.Spec = recorder
+	chaoskube.Calendars = []Calendar{&ConfigMapCalendar{Namespace: "marmoset", Name: "holidays"}}
+
+	// without the ConfigMap, chaos is suspended
+	err := chaoskube.TerminateVictim(context.Background())
+	suite.Error(err)
+	suite.False(recorder.invoked)
+
+	// with a ConfigMap listing today, chaos is suspended
+	configMap, err := chaoskube.Client.CoreV1().ConfigMaps("marmoset").Create(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "marmoset", Name: "holidays"},
+		Data:       map[string]string{"holidays.ics": blackFridayCalendar},
+	})
+	suite.Require().NoError(err)
+
+	err = chaoskube.TerminateVictim(context.Background())
+	suite.Require().NoError(err)
+	suite.False(recorder.invoked)
+	suite.assertLog(log.DebugLevel, msgDateExcluded, log.Fields{"dateRange": "1869-09-24 (Black Friday)"})
+
+	// once the day is removed, chaos continues
+	configMap.Data = map[string]string{}
+	_, err = chaoskube.Client.CoreV1().ConfigMaps("marmoset").Update(configMap)
+	suite.Require().NoError(err)
+
+	err = chaoskube.TerminateVictim(context.Background())
+	suite.Require().NoError(err)
+	suite.True(recorder.invoked)
+}
+
+// TestTerminateVictimFileCalendar tests that calendars are read from disk
+func (suite *Suite) TestTerminateVictimFileCalendar() {
+	file, err := ioutil.TempFile("", "holidays")
+	suite.Require().NoError(err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(blackFridayCalendar)
+	suite.Require().NoError(err)
+	suite.Require().NoError(file.Close())
+
+	chaoskube := suite.setupOnFriday()
+	recorder := &chaosRecorder{}
+	chaoskube.Spec = recorder
+	chaoskube.Calendars = []Calendar{&FileCalendar{Path: file.Name()}}
+
+	err = chaoskube.TerminateVictim(context.Background())
+	suite.Require().NoError(err)
+	suite.False(recorder.invoked)
+}
+
+func (suite *Suite) TestParseConfigMapCalendar() {
+	calendar, err := ParseConfigMapCalendar("marmoset/holidays")
+	suite.Require().NoError(err)
+	suite.Equal(&ConfigMapCalendar{Namespace: "marmoset", Name: "holidays"}, calendar)
+
+	calendar, err = ParseConfigMapCalendar("marmoset/holidays:freezes.ics")
+	suite.Require().NoError(err)
+	suite.Equal(&ConfigMapCalendar{Namespace: "marmoset", Name: "holidays", Key: "freezes.ics"}, calendar)
+
+	for _, invalid := range []string{"", "holidays", "/holidays", "marmoset/"} {
+		_, err := ParseConfigMapCalendar(invalid)
+		suite.Error(err, invalid)
+	}
+}
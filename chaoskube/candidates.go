@@ -0,0 +1,93 @@
+package chaoskube
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"k8s.io/api/core/v1"
+)
+
+const (
+	// Names of the pod filters, in the order they are applied
+	FilterNode            = "node"
+	FilterNamespace       = "namespace"
+	FilterNamespaceLabels = "namespace labels"
+	FilterAnnotation      = "annotation"
+	FilterOptIn           = "opt-in"
+	FilterPhase           = "phase"
+	FilterReady           = "ready"
+	FilterRestarts        = "restarts"
+	FilterQOSClass        = "QoS class"
+	FilterPriority        = "priority"
+	FilterImage           = "image"
+	FilterMinimumAge      = "minimum age"
+	FilterOwner           = "owner"
+	// Names of the filters applied to pods and nodes alike
+	FilterBlastRadius = "blast radius"
+)
+
+// Candidate is a pod or node a ChaosSpec may pick as its next victim
+type Candidate struct {
+	// "pod" or "node"
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// why the candidate passed the filters
+	Reason string `json:"reason"`
+}
+
+// FilterCount tells how many objects a filter removed
+type FilterCount struct {
+	Filter  string `json:"filter"`
+	Removed int    `json:"removed"`
+}
+
+// CandidateReport lists the candidates of a ChaosSpec, and how the filters got there
+type CandidateReport struct {
+	// how many objects were listed before filtering
+	Listed int `json:"listed"`
+	// how many objects each filter removed, in the order the filters are applied
+	Filters []FilterCount `json:"filters"`
+	// what is left
+	Candidates []Candidate `json:"candidates"`
+}
+
+func newCandidateReport(listed int) *CandidateReport {
+	return &CandidateReport{
+		Listed:     listed,
+		Filters:    []FilterCount{},
+		Candidates: []Candidate{},
+	}
+}
+
+// removed counts the pods a filter removed and passes on the ones it kept
+func (r *CandidateReport) removed(filter string, before, after []v1.Pod) []v1.Pod {
+	r.Filters = append(r.Filters, FilterCount{Filter: filter, Removed: len(before) - len(after)})
+	return after
+}
+
+// removedNodes counts the nodes a filter removed and passes on the ones it kept
+func (r *CandidateReport) removedNodes(filter string, before, after []v1.Node) []v1.Node {
+	r.Filters = append(r.Filters, FilterCount{Filter: filter, Removed: len(before) - len(after)})
+	return after
+}
+
+// Print writes the report as a human-readable table
+func (r *CandidateReport) Print(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(table, "KIND\tNAMESPACE\tNAME\tREASON")
+	for _, c := range r.Candidates {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", c.Kind, c.Namespace, c.Name, c.Reason)
+	}
+	fmt.Fprintln(table)
+
+	fmt.Fprintf(table, "listed\t%d\n", r.Listed)
+	for _, f := range r.Filters {
+		fmt.Fprintf(table, "removed by %s\t%d\n", f.Filter, f.Removed)
+	}
+	fmt.Fprintf(table, "candidates\t%d\n", len(r.Candidates))
+
+	return table.Flush()
+}
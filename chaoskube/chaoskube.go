@@ -3,7 +3,9 @@ package chaoskube
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/neo-technology/marmoset/util"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -23,12 +25,85 @@ type Chaoskube struct {
 	ExcludedTimesOfDay []util.TimePeriod
 	// a list of days of a year when chaos is suspended
 	ExcludedDaysOfYear []time.Time
+	// a list of days or ranges of days, yearly or one-off, when chaos is suspended
+	ExcludedDateRanges []util.DateRange
+	// calendars of days when chaos is suspended, e.g. holidays and change freezes
+	Calendars []Calendar
+	// if not empty, chaos is suspended outside of these windows; exclusions take precedence
+	AllowedWindows []util.Window
+	// an optional kill switch, checked before anything else on every run
+	KillSwitch *KillSwitch
+	// an optional gate suppressing chaos while the cluster is already degraded
+	HealthGate *HealthGate
 	// the timezone to apply when detecting the current weekday
 	Timezone *time.Location
 	// an instance of logrus.StdLogger to write log messages to
 	Logger log.FieldLogger
 	// a function to retrieve the current time
 	Now func() time.Time
+	// how many outcomes of recent runs to keep for Outcomes
+	OutcomesKept int
+	// a durable record of every run; optional
+	History History
+	// the experiment runs belong to, for the History and steady-state Events; optional
+	Experiment *Experiment
+	// the steady-state hypothesis: checked before each action, which is skipped unless all
+	// probes pass, and again after it
+	Probes []Probe
+	// whether to pause chaos when the steady state isn't restored after an action
+	PauseOnProbeFailure bool
+
+	// serializes runs
+	runMutex sync.Mutex
+	// guards the fields below
+	stateMutex sync.Mutex
+	// a pause made through Pause, if any
+	pause *Pause
+	// the pause the kill switch was last found in, if any
+	killSwitchPause *Pause
+	// the outcomes of recent runs, oldest first
+	outcomes []Outcome
+}
+
+// Outcome is the result of one run
+type Outcome struct {
+	// when the run started
+	Time time.Time `json:"time"`
+	// the victims chaos was imbued in, if any
+	Victims []*Victim `json:"victims,omitempty"`
+	// why the run was skipped, if it was
+	Exclusion *Exclusion `json:"exclusion,omitempty"`
+	// why the run failed, if it did
+	Error string `json:"error,omitempty"`
+}
+
+// Exclusion tells why chaos is suspended
+type Exclusion struct {
+	// the log message, e.g. "weekday excluded"
+	Reason string `json:"reason"`
+	// what exactly caused the exclusion, e.g. the weekday or the date range
+	Details log.Fields `json:"details,omitempty"`
+
+	// the reason label of the skipped runs metric, if the exclusion is counted there
+	skipReason string
+}
+
+func excluded(reason string, details log.Fields) *Exclusion {
+	return &Exclusion{Reason: reason, Details: details}
+}
+
+func pauseExclusion(pause *Pause, err error) *Exclusion {
+	details := log.Fields{
+		"reason": pause.Reason,
+		"source": pause.Source,
+	}
+	if !pause.Until.IsZero() {
+		details["until"] = pause.Until.Format(time.RFC3339)
+	}
+	if err != nil {
+		details["err"] = err.Error()
+	}
+	return &Exclusion{Reason: msgPaused, Details: details, skipReason: skipReasonPaused}
 }
 
 var (
@@ -42,6 +117,21 @@ var (
 	msgTimeOfDayExcluded = "time of day excluded"
 	// msgDayOfYearExcluded is the log message when termination is suspended due to the day of year filter
 	msgDayOfYearExcluded = "day of year excluded"
+	// msgDateExcluded is the log message when termination is suspended due to a date range or calendar
+	msgDateExcluded = "date excluded"
+	// msgOutsideAllowedWindows is the log message when termination is suspended because no allowed window is open
+	msgOutsideAllowedWindows = "outside allowed windows"
+	// msgPaused is the log message when termination is suspended through the API or by the kill switch
+	msgPaused = "chaos paused"
+	// msgSteadyStateNotMet is the log message when termination is skipped because a probe failed
+	msgSteadyStateNotMet = "steady state not met"
+)
+
+const (
+	// defaultOutcomesKept is how many outcomes of recent runs are kept by default
+	defaultOutcomesKept = 20
+	// pauseSourceAPI is the source of pauses made through Pause
+	pauseSourceAPI = "api"
 )
 
 // New returns a new instance of Chaoskube. It expects:
@@ -61,13 +151,14 @@ func New(client kubernetes.Interface, spec ChaosSpec, excludedWeekdays []time.We
 		Timezone:           timezone,
 		Logger:             logger,
 		Now:                time.Now,
+		OutcomesKept:       defaultOutcomesKept,
 	}
 }
 
 // Run continuously picks and terminates a victim pod at a given interval
 // described by channel next. It returns when the given context is canceled.
 func (c *Chaoskube) Run(ctx context.Context, next <-chan time.Time) {
-	initErr := c.Spec.Init(c.Client)
+	initErr := c.Spec.Init(ctx, c.Client)
 	if initErr != nil {
 		c.Logger.WithField("err", initErr).Error("init failed")
 		return
@@ -81,42 +172,303 @@ func (c *Chaoskube) Run(ctx context.Context, next <-chan time.Time) {
 			return
 		}
 
-		if err := c.TerminateVictim(); err != nil {
+		if err := c.TerminateVictim(ctx); err != nil {
 			c.Logger.WithField("err", err).Error("failed to terminate victim")
 		}
 	}
 }
 
 // TerminateVictim picks and deletes a victim.
-// It respects the configured excluded weekdays, times of day and days of a year filters.
-func (c *Chaoskube) TerminateVictim() error {
+// Nothing happens while chaos is paused, through the API or the kill switch. Otherwise it respects
+// the configured excluded weekdays, times of day, days of a year, date ranges and calendars, and
+// after those the allowed windows: an exclusion always wins over an allowed window. Last, the
+// health gate suppresses chaos while the cluster is already degraded.
+// If a calendar or the health of the cluster can't be read, chaos is suspended rather than risking
+// a run during a freeze or an outage. Cancelling the context stops the action as soon as it can.
+func (c *Chaoskube) TerminateVictim(ctx context.Context) error {
+	_, err := c.RunOnce(ctx)
+	return err
+}
+
+// RunOnce is TerminateVictim, also returning the outcome of the run. Runs never overlap, so a run
+// triggered through the API waits for a scheduled one to finish and vice versa.
+func (c *Chaoskube) RunOnce(ctx context.Context) (Outcome, error) {
+	c.runMutex.Lock()
+	defer c.runMutex.Unlock()
+
+	start := time.Now()
 	now := c.Now().In(c.Timezone)
+	outcome := Outcome{Time: now}
+
+	victims, err := c.terminateVictim(ctx, now, &outcome)
+	outcome.Victims = victims
+	if err != nil {
+		outcome.Error = err.Error()
+	}
+
+	c.recordOutcome(outcome)
+	if c.History != nil {
+		experiment := ""
+		if c.Experiment != nil {
+			experiment = c.Experiment.Name
+		}
+		for _, record := range NewRecords(outcome, experiment, actionOf(c.Spec), time.Since(start)) {
+			if err := c.History.Append(record); err != nil {
+				c.Logger.WithField("err", err).Error("failed to write history")
+			}
+		}
+	}
+	return outcome, err
+}
+
+// actionOf returns the name of the action a spec applies, if it is known
+func actionOf(spec ChaosSpec) string {
+	switch s := spec.(type) {
+	case *PodChaosSpec:
+		return s.Action.Name()
+	case *NodeChaosSpec:
+		return s.Action.Name()
+	}
+	return ""
+}
+
+func (c *Chaoskube) terminateVictim(ctx context.Context, now time.Time, outcome *Outcome) ([]*Victim, error) {
+	exclusion, err := c.Exclusion(now)
+	if err != nil {
+		return nil, err
+	}
+	if exclusion != nil {
+		// the time-based exclusions are routine, anything else is worth telling about
+		if exclusion.skipReason != "" {
+			skippedRuns.WithLabelValues(exclusion.skipReason).Inc()
+			c.Logger.WithFields(exclusion.Details).Info(exclusion.Reason)
+		} else {
+			c.Logger.WithFields(exclusion.Details).Debug(exclusion.Reason)
+		}
+		outcome.Exclusion = exclusion
+		return nil, nil
+	}
+
+	if err := checkProbes(ctx, c.Client, c.Probes); err != nil {
+		probeFailures.WithLabelValues(probePhaseBefore).Inc()
+		skippedRuns.WithLabelValues(skipReasonSteadyState).Inc()
+		exclusion := &Exclusion{Reason: msgSteadyStateNotMet, Details: log.Fields{"err": err.Error()}, skipReason: skipReasonSteadyState}
+		c.Logger.WithFields(exclusion.Details).Info(exclusion.Reason)
+		outcome.Exclusion = exclusion
+		return nil, nil
+	}
+
+	victims, err := c.Spec.Apply(ctx, c.Client, c.Now())
+	if err == errPodNotFound || (err == nil && len(victims) == 0) {
+		c.Logger.Debug(msgVictimNotFound)
+		return nil, nil
+	}
+	if len(victims) == 0 {
+		return victims, err
+	}
+
+	// a failed action may still have done harm, so the steady state is checked either way
+	if probeErr := checkProbes(ctx, c.Client, c.Probes); probeErr != nil {
+		probeErr = c.steadyStateViolated(victims, probeErr)
+		if err != nil {
+			return victims, fmt.Errorf("%s; %s", err, probeErr)
+		}
+		return victims, probeErr
+	}
+	return victims, err
+}
+
+// steadyStateViolated handles a probe failing after an action: it marks the victims as failed,
+// tells their owners, pauses chaos if configured to, and returns the error of the run
+func (c *Chaoskube) steadyStateViolated(victims []*Victim, err error) error {
+	probeFailures.WithLabelValues(probePhaseAfter).Inc()
+	for _, victim := range victims {
+		if victim.Error == "" {
+			victim.Error = fmt.Sprintf("steady state not restored: %s", err)
+		}
+		if c.Experiment != nil {
+			c.Experiment.steadyStateViolated(victim, err)
+		}
+	}
+
+	if c.PauseOnProbeFailure {
+		victim := victims[0]
+		reason := fmt.Sprintf("steady state not restored after %s of %s/%s", victim.Action, victim.Namespace, victim.Name)
+		if len(victims) > 1 {
+			reason = fmt.Sprintf("steady state not restored after %s of %d victims", victim.Action, len(victims))
+		}
+		c.Pause(0, reason)
+		c.Logger.WithFields(log.Fields{
+			"reason": reason,
+			"err":    err,
+		}).Warn(msgPaused)
+	}
+	return fmt.Errorf("steady state not restored: %s", err)
+}
+
+// Exclusion returns why chaos is suspended at the given point in time, or nil if it isn't. An
+// error means it can't be told for sure, which suspends chaos as well.
+func (c *Chaoskube) Exclusion(now time.Time) (*Exclusion, error) {
+	if pause := c.currentPause(now); pause != nil {
+		return pauseExclusion(pause, nil), nil
+	}
+
+	if c.KillSwitch != nil {
+		pause, err := c.KillSwitch.Check(c.Client, now)
+		if pause != nil || err == nil {
+			// a kill switch that can't be read is left as it was last seen
+			c.setKillSwitchPause(pause)
+		}
+		if pause != nil {
+			return pauseExclusion(pause, err), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to check kill switch, suspending chaos: %s", err)
+		}
+	}
 
 	for _, wd := range c.ExcludedWeekdays {
 		if wd == now.Weekday() {
-			c.Logger.WithField("weekday", now.Weekday()).Debug(msgWeekdayExcluded)
-			return nil
+			return excluded(msgWeekdayExcluded, log.Fields{"weekday": now.Weekday()}), nil
 		}
 	}
 
 	for _, tp := range c.ExcludedTimesOfDay {
 		if tp.Includes(now) {
-			c.Logger.WithField("timeOfDay", now.Format(util.Kitchen24)).Debug(msgTimeOfDayExcluded)
-			return nil
+			return excluded(msgTimeOfDayExcluded, log.Fields{"timeOfDay": now.Format(util.Kitchen24)}), nil
 		}
 	}
 
 	for _, d := range c.ExcludedDaysOfYear {
 		if d.Day() == now.Day() && d.Month() == now.Month() {
-			c.Logger.WithField("dayOfYear", now.Format(util.YearDay)).Debug(msgDayOfYearExcluded)
-			return nil
+			return excluded(msgDayOfYearExcluded, log.Fields{"dayOfYear": now.Format(util.YearDay)}), nil
 		}
 	}
 
-	err := c.Spec.Apply(c.Client, c.Now())
-	if err == errPodNotFound {
-		c.Logger.Debug(msgVictimNotFound)
+	for _, r := range c.ExcludedDateRanges {
+		if r.Includes(now) {
+			return excluded(msgDateExcluded, log.Fields{"dateRange": r.String()}), nil
+		}
+	}
+
+	for _, calendar := range c.Calendars {
+		ranges, err := calendar.Ranges(c.Client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read calendar %s, suspending chaos: %s", calendar, err)
+		}
+		for _, r := range ranges {
+			if r.Includes(now) {
+				return excluded(msgDateExcluded, log.Fields{
+					"dateRange": r.String(),
+					"calendar":  calendar.String(),
+				}), nil
+			}
+		}
+	}
+
+	if len(c.AllowedWindows) > 0 && !c.insideAllowedWindow(now) {
+		return excluded(msgOutsideAllowedWindows, log.Fields{"time": now.Format(time.RFC3339)}), nil
+	}
+
+	if c.HealthGate != nil {
+		exclusion, err := c.HealthGate.Check(c.Client, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check cluster health, suspending chaos: %s", err)
+		}
+		if exclusion != nil {
+			return exclusion, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Pause suspends chaos for the given duration, or until Resume is called if it is zero
+func (c *Chaoskube) Pause(duration time.Duration, reason string) Pause {
+	pause := Pause{Reason: reason, Source: pauseSourceAPI}
+	if duration > 0 {
+		pause.Until = c.Now().Add(duration)
+		// runs may be hours apart, so the gauge doesn't wait for the next one to see it end
+		time.AfterFunc(duration, c.updatePaused)
+	}
+
+	c.stateMutex.Lock()
+	c.pause = &pause
+	c.stateMutex.Unlock()
+
+	c.updatePaused()
+	return pause
+}
+
+// Resume lifts a pause made through Pause. It doesn't affect the kill switch.
+func (c *Chaoskube) Resume() {
+	c.stateMutex.Lock()
+	c.pause = nil
+	c.stateMutex.Unlock()
+
+	c.updatePaused()
+}
+
+func (c *Chaoskube) setKillSwitchPause(pause *Pause) {
+	c.stateMutex.Lock()
+	c.killSwitchPause = pause
+	c.stateMutex.Unlock()
+
+	c.updatePaused()
+}
+
+// updatePaused sets the paused gauge to whether chaos is paused, through Pause or by the kill switch
+func (c *Chaoskube) updatePaused() {
+	now := c.Now()
+	killSwitchPaused := false
+
+	c.stateMutex.Lock()
+	if pause := c.killSwitchPause; pause != nil {
+		killSwitchPaused = pause.Until.IsZero() || now.Before(pause.Until)
+	}
+	c.stateMutex.Unlock()
+
+	if killSwitchPaused || c.currentPause(now) != nil {
+		paused.Set(1)
+	} else {
+		paused.Set(0)
+	}
+}
+
+func (c *Chaoskube) currentPause(now time.Time) *Pause {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	if c.pause == nil || (!c.pause.Until.IsZero() && !now.Before(c.pause.Until)) {
 		return nil
 	}
-	return err
+	pause := *c.pause
+	return &pause
+}
+
+// Outcomes returns the outcomes of the most recent runs, oldest first
+func (c *Chaoskube) Outcomes() []Outcome {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	return append([]Outcome{}, c.outcomes...)
+}
+
+func (c *Chaoskube) recordOutcome(outcome Outcome) {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	c.outcomes = append(c.outcomes, outcome)
+	if len(c.outcomes) > c.OutcomesKept {
+		c.outcomes = c.outcomes[len(c.outcomes)-c.OutcomesKept:]
+	}
+}
+
+func (c *Chaoskube) insideAllowedWindow(now time.Time) bool {
+	for _, w := range c.AllowedWindows {
+		if w.Includes(now) {
+			return true
+		}
+	}
+	return false
 }
@@ -131,13 +131,16 @@ type countingSpec struct {
 	initCallCount uint64
 }
 
-func (c *countingSpec) Init(k8sclient clientset.Interface) error {
+func (c *countingSpec) Init(ctx context.Context, k8sclient clientset.Interface) error {
 	atomic.AddUint64(&c.initCallCount, 1)
 	return nil
 }
-func (c *countingSpec) Apply(k8sclient clientset.Interface, now time.Time) error {
+func (c *countingSpec) Apply(ctx context.Context, k8sclient clientset.Interface, now time.Time) ([]*Victim, error) {
 	atomic.AddUint64(&c.counter, 1)
-	return nil
+	return nil, nil
+}
+func (c *countingSpec) Candidates(k8sclient clientset.Interface, now time.Time) (*CandidateReport, error) {
+	return newCandidateReport(0), nil
 }
 func (c *countingSpec) currentCount() uint64 {
 	return atomic.LoadUint64(&c.counter)
@@ -371,16 +374,136 @@ func (suite *Suite) TestTerminateVictim() {
 		chaoskube.Spec = recorder
 		chaoskube.Now = tt.now
 
-		err := chaoskube.TerminateVictim()
+		err := chaoskube.TerminateVictim(context.Background())
 		suite.Require().NoError(err)
 
-		err = chaoskube.TerminateVictim()
+		err = chaoskube.TerminateVictim(context.Background())
 		suite.Require().NoError(err)
 
 		suite.Require().Equal(tt.expectSpecInvoked, recorder.invoked)
 	}
 }
 
+// TestTerminateVictimAllowedWindows tests that allowed windows restrict chaos and lose against exclusions
+func (suite *Suite) TestTerminateVictimAllowedWindows() {
+	friday := []time.Weekday{time.Friday}
+	afternoon := util.NewTimePeriod(
+		ThankGodItsFriday{}.Now().Add(-1*time.Hour),
+		ThankGodItsFriday{}.Now().Add(+1*time.Hour),
+	)
+	morning := util.NewTimePeriod(
+		ThankGodItsFriday{}.Now().Add(-7*time.Hour),
+		ThankGodItsFriday{}.Now().Add(-6*time.Hour),
+	)
+
+	for _, tt := range []struct {
+		allowedWindows     []util.Window
+		excludedTimesOfDay []util.TimePeriod
+		expectSpecInvoked  bool
+		expectedLog        string
+	}{
+		// no windows means chaos is always allowed
+		{nil, nil, true, ""},
+		// inside the only window
+		{[]util.Window{{Weekdays: friday, Period: afternoon}}, nil, true, ""},
+		// outside the only window
+		{[]util.Window{{Weekdays: friday, Period: morning}}, nil, false, msgOutsideAllowedWindows},
+		// right time of day, wrong weekday
+		{[]util.Window{{Weekdays: []time.Weekday{time.Monday}, Period: afternoon}}, nil, false, msgOutsideAllowedWindows},
+		// inside one of several windows
+		{[]util.Window{{Weekdays: friday, Period: morning}, {Weekdays: friday, Period: afternoon}}, nil, true, ""},
+		// inside a window, but excluded
+		{[]util.Window{{Weekdays: friday, Period: afternoon}}, []util.TimePeriod{afternoon}, false, msgTimeOfDayExcluded},
+	} {
+		chaoskube := suite.setup(
+			labels.Everything(),
+			labels.Everything(),
+			labels.Everything(),
+			[]time.Weekday{},
+			tt.excludedTimesOfDay,
+			[]time.Time{},
+			time.UTC,
+			time.Duration(0),
+			false,
+		)
+		recorder := &chaosRecorder{}
+		chaoskube.Spec = recorder
+		chaoskube.Now = ThankGodItsFriday{}.Now
+		chaoskube.AllowedWindows = tt.allowedWindows
+
+		err := chaoskube.TerminateVictim(context.Background())
+		suite.Require().NoError(err)
+
+		suite.Equal(tt.expectSpecInvoked, recorder.invoked)
+		if tt.expectedLog != "" {
+			suite.assertLog(log.DebugLevel, tt.expectedLog, log.Fields{})
+		}
+	}
+}
+
+// TestPauseAndResume tests that a pause suspends chaos until it is lifted or expires
+func (suite *Suite) TestPauseAndResume() {
+	chaoskube := suite.setupOnFriday()
+	recorder := &chaosRecorder{}
+	chaoskube.Spec = recorder
+
+	chaoskube.Pause(0, "game day")
+	suite.Require().NoError(chaoskube.TerminateVictim(context.Background()))
+	suite.False(recorder.invoked)
+	suite.assertLog(log.InfoLevel, msgPaused, log.Fields{"reason": "game day", "source": "api"})
+
+	chaoskube.Resume()
+	suite.Require().NoError(chaoskube.TerminateVictim(context.Background()))
+	suite.True(recorder.invoked)
+
+	// a pause with a duration ends by itself
+	recorder.invoked = false
+	chaoskube.Pause(1*time.Hour, "")
+	suite.Require().NoError(chaoskube.TerminateVictim(context.Background()))
+	suite.False(recorder.invoked)
+
+	chaoskube.Now = func() time.Time { return ThankGodItsFriday{}.Now().Add(1 * time.Hour) }
+	suite.Require().NoError(chaoskube.TerminateVictim(context.Background()))
+	suite.True(recorder.invoked)
+}
+
+// TestRunOnceOutcomes tests that the outcomes of recent runs are kept
+func (suite *Suite) TestRunOnceOutcomes() {
+	chaoskube := suite.setupWithPods(
+		labels.Everything(),
+		labels.Everything(),
+		labels.Everything(),
+		[]time.Weekday{},
+		[]util.TimePeriod{},
+		[]time.Time{},
+		time.UTC,
+		time.Duration(0),
+		true,
+	)
+	chaoskube.Now = ThankGodItsFriday{}.Now
+	chaoskube.OutcomesKept = 2
+
+	outcome, err := chaoskube.RunOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.Require().Len(outcome.Victims, 1)
+	suite.Equal(KindPod, outcome.Victims[0].Kind)
+	suite.Nil(outcome.Exclusion)
+
+	chaoskube.ExcludedWeekdays = []time.Weekday{time.Friday}
+	outcome, err = chaoskube.RunOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.Empty(outcome.Victims)
+	suite.Require().NotNil(outcome.Exclusion)
+	suite.Equal(msgWeekdayExcluded, outcome.Exclusion.Reason)
+
+	chaoskube.RunOnce(context.Background())
+
+	outcomes := chaoskube.Outcomes()
+	suite.Require().Len(outcomes, 2)
+	suite.NotNil(outcomes[0].Exclusion)
+	suite.NotNil(outcomes[1].Exclusion)
+}
+
 // TestTerminateNoVictimLogsInfo tests that missing victim prints a log message
 func (suite *Suite) TestTerminateNoVictimLogsInfo() {
 	chaoskube := suite.setup(
@@ -395,7 +518,7 @@ func (suite *Suite) TestTerminateNoVictimLogsInfo() {
 		false,
 	)
 
-	err := chaoskube.TerminateVictim()
+	err := chaoskube.TerminateVictim(context.Background())
 	suite.Require().NoError(err)
 
 	suite.assertLog(log.DebugLevel, msgVictimNotFound, log.Fields{})
@@ -416,7 +539,7 @@ func (suite *Suite) TestDryRunIsInert() {
 	client := chaoskube.Client.(*fake.Clientset)
 	client.Fake.ClearActions() // Clear the actions taken by the setup code
 
-	err := chaoskube.TerminateVictim()
+	err := chaoskube.TerminateVictim(context.Background())
 	suite.Require().NoError(err)
 
 	suite.assertLog(log.InfoLevel, "dry run", log.Fields{})
@@ -435,13 +558,17 @@ type chaosRecorder struct {
 	invoked bool
 }
 
-func (r *chaosRecorder) Init(k8sclient clientset.Interface) error {
+func (r *chaosRecorder) Init(ctx context.Context, k8sclient clientset.Interface) error {
 	return nil
 }
 
-func (r *chaosRecorder) Apply(k8sclient clientset.Interface, now time.Time) error {
+func (r *chaosRecorder) Apply(ctx context.Context, k8sclient clientset.Interface, now time.Time) ([]*Victim, error) {
 	r.invoked = true
-	return nil
+	return nil, nil
+}
+
+func (r *chaosRecorder) Candidates(k8sclient clientset.Interface, now time.Time) (*CandidateReport, error) {
+	return newCandidateReport(0), nil
 }
 
 var _ ChaosSpec = &chaosRecorder{}
@@ -525,6 +652,23 @@ func (suite *Suite) setup(labelSelector labels.Selector, annotations labels.Sele
 	)
 }
 
+// setupOnFriday returns a Chaoskube with no exclusions at all that thinks it's always ThankGodItsFriday
+func (suite *Suite) setupOnFriday() *Chaoskube {
+	chaoskube := suite.setup(
+		labels.Everything(),
+		labels.Everything(),
+		labels.Everything(),
+		[]time.Weekday{},
+		[]util.TimePeriod{},
+		[]time.Time{},
+		time.UTC,
+		time.Duration(0),
+		false,
+	)
+	chaoskube.Now = ThankGodItsFriday{}.Now
+	return chaoskube
+}
+
 func TestSuite(t *testing.T) {
 	suite.Run(t, new(Suite))
 }
@@ -0,0 +1,104 @@
+package chaoskube
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ClusterCache keeps the pods, nodes and namespaces of a cluster in memory, and up to date by
+// watching them, so finding candidates doesn't list the whole cluster on every run
+type ClusterCache struct {
+	factory    informers.SharedInformerFactory
+	pods       corelisters.PodLister
+	nodes      corelisters.NodeLister
+	namespaces corelisters.NamespaceLister
+	// tell whether each informer listed its objects yet
+	synced []cache.InformerSynced
+}
+
+// NewClusterCache returns a cache of the pods, nodes and namespaces the client can see. It has to
+// be started before use.
+func NewClusterCache(client kubernetes.Interface) *ClusterCache {
+	// the listers only read what the watches deliver, so there is nothing to resync
+	factory := informers.NewSharedInformerFactory(client, 0)
+	pods := factory.Core().V1().Pods()
+	nodes := factory.Core().V1().Nodes()
+	namespaces := factory.Core().V1().Namespaces()
+
+	return &ClusterCache{
+		factory:    factory,
+		pods:       pods.Lister(),
+		nodes:      nodes.Lister(),
+		namespaces: namespaces.Lister(),
+		synced: []cache.InformerSynced{
+			pods.Informer().HasSynced,
+			nodes.Informer().HasSynced,
+			namespaces.Informer().HasSynced,
+		},
+	}
+}
+
+// Start watches the cluster until the context is done, and waits for the first list of each kind
+// of object. Starting the cache again only waits.
+func (c *ClusterCache) Start(ctx context.Context) error {
+	c.factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), c.synced...) {
+		return fmt.Errorf("gave up waiting for the cluster cache to fill: %s", ctx.Err())
+	}
+	return nil
+}
+
+// Pods returns the pods matching the selector, sorted by namespace and name like the API server
+// lists them. They share their maps and slices with the cache, so must not be modified.
+func (c *ClusterCache) Pods(selector labels.Selector) ([]v1.Pod, error) {
+	cached, err := c.pods.List(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	pods := make([]v1.Pod, 0, len(cached))
+	for _, pod := range cached {
+		pods = append(pods, *pod)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].Namespace != pods[j].Namespace {
+			return pods[i].Namespace < pods[j].Namespace
+		}
+		return pods[i].Name < pods[j].Name
+	})
+	return pods, nil
+}
+
+// Nodes returns the nodes matching the selector, sorted by name. They share their maps and slices
+// with the cache, so must not be modified.
+func (c *ClusterCache) Nodes(selector labels.Selector) ([]v1.Node, error) {
+	cached, err := c.nodes.List(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]v1.Node, 0, len(cached))
+	for _, node := range cached {
+		nodes = append(nodes, *node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	return nodes, nil
+}
+
+// Namespace returns the namespace of the given name, or nil if there is none
+func (c *ClusterCache) Namespace(name string) (*v1.Namespace, error) {
+	namespace, err := c.namespaces.Get(name)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return namespace, err
+}
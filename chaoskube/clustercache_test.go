@@ -0,0 +1,119 @@
+package chaoskube
+
+import (
+	"context"
+	"sort"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// podInNamespace returns a running pod on the given node
+func podInNamespace(namespace, name, node string, podLabels map[string]string) *v1.Pod {
+	pod := podOnNode(name, node)
+	pod.Namespace = namespace
+	pod.Labels = podLabels
+	return pod
+}
+
+// sortedCandidates orders candidates by namespace and name, as neither the cache nor the fake
+// client promise an order
+func sortedCandidates(candidates []Candidate) []Candidate {
+	sorted := append([]Candidate{}, candidates...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Namespace != sorted[j].Namespace {
+			return sorted[i].Namespace < sorted[j].Namespace
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+func clusterClient() *fake.Clientset {
+	return fake.NewSimpleClientset(
+		labelledNamespace("graph", map[string]string{"env": "test"}),
+		labelledNamespace("web", map[string]string{"env": "prod"}),
+		labelledNode("spot-1", map[string]string{"pool": "spot"}),
+		labelledNode("regular-1", map[string]string{"pool": "regular"}),
+		podInNamespace("web", "frontend", "spot-1", map[string]string{"app": "frontend"}),
+		podInNamespace("graph", "neo4j-1", "regular-1", map[string]string{"app": "neo4j"}),
+		podInNamespace("graph", "neo4j-0", "spot-1", map[string]string{"app": "neo4j"}),
+		podInNamespace("gone", "orphan", "spot-1", map[string]string{"app": "neo4j"}),
+	)
+}
+
+func (suite *Suite) TestClusterCache() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewClusterCache(clusterClient())
+	suite.Require().NoError(cache.Start(ctx))
+
+	pods, err := cache.Pods(labels.SelectorFromSet(labels.Set{"app": "neo4j"}))
+	suite.Require().NoError(err)
+	suite.Equal([]string{"orphan", "neo4j-0", "neo4j-1"}, podNames(pods))
+
+	nodes, err := cache.Nodes(labels.Everything())
+	suite.Require().NoError(err)
+	suite.Require().Len(nodes, 2)
+	suite.Equal("regular-1", nodes[0].Name)
+
+	namespace, err := cache.Namespace("graph")
+	suite.Require().NoError(err)
+	suite.Equal("test", namespace.Labels["env"])
+
+	namespace, err = cache.Namespace("gone")
+	suite.Require().NoError(err)
+	suite.Nil(namespace)
+}
+
+// TestPodChaosSpecClusterCache tests that candidates from the cache are the ones listed, without
+// listing anything
+func (suite *Suite) TestPodChaosSpecClusterCache() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	friday := ThankGodItsFriday{}.Now()
+
+	for _, tt := range []struct {
+		labels          string
+		namespaceLabels string
+		nodeLabels      string
+	}{
+		{"", "", ""},
+		{"app=neo4j", "", ""},
+		{"", "env=test", ""},
+		{"", "", "pool=spot"},
+		{"app=neo4j", "env", "pool=regular"},
+	} {
+		spec := func() *PodChaosSpec {
+			spec := &PodChaosSpec{
+				Annotations: labels.Everything(),
+				Namespaces:  labels.Everything(),
+				Logger:      logger,
+			}
+			var err error
+			spec.Labels, err = labels.Parse(tt.labels)
+			suite.Require().NoError(err)
+			spec.NamespaceLabels, err = labels.Parse(tt.namespaceLabels)
+			suite.Require().NoError(err)
+			spec.NodeLabels, err = labels.Parse(tt.nodeLabels)
+			suite.Require().NoError(err)
+			return spec
+		}
+
+		listed, err := spec().Candidates(clusterClient(), friday)
+		suite.Require().NoError(err)
+
+		client := clusterClient()
+		cached := spec()
+		cached.Cache = NewClusterCache(client)
+		suite.Require().NoError(cached.Cache.Start(ctx))
+		actions := len(client.Actions())
+
+		report, err := cached.Candidates(client, friday)
+		suite.Require().NoError(err)
+		suite.Equal(sortedCandidates(listed.Candidates), sortedCandidates(report.Candidates), tt)
+		suite.Len(client.Actions(), actions, tt)
+	}
+}
@@ -0,0 +1,166 @@
+package chaoskube
+
+import (
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// the component marmoset reports Events as
+	eventComponent = "marmoset"
+	// Event reasons
+	ReasonChaosApplied        = "ChaosApplied"
+	ReasonChaosFailed         = "ChaosFailed"
+	ReasonSteadyStateViolated = "SteadyStateViolated"
+)
+
+// Experiment names a chaos experiment and tells the owners of its victims about it, by recording
+// Kubernetes Events on them and notifying webhooks
+type Experiment struct {
+	// the name of the experiment, e.g. "kill-frontend"
+	Name string
+	// identifies the marmoset instance running the experiment
+	Instance string
+	// records the Events; optional
+	Recorder record.EventRecorder
+	// notified before and after each action; optional
+	Webhooks *Webhooks
+}
+
+// NewExperiment returns an Experiment recording Events with the given recorder
+func NewExperiment(name, instance string, recorder record.EventRecorder) *Experiment {
+	return &Experiment{
+		Name:     name,
+		Instance: instance,
+		Recorder: recorder,
+	}
+}
+
+// NewEventRecorder returns a recorder creating Events through the given client, in the background.
+// Each Event is created in the namespace of the object it is about.
+func NewEventRecorder(client kubernetes.Interface, instance string, logger log.FieldLogger) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartLogging(logger.Debugf)
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: eventComponent, Host: instance})
+}
+
+// applying is called right before an action is applied to a victim
+func (e *Experiment) applying(victim *Victim) {
+	e.notify(WebhookBefore, victim, nil)
+}
+
+// podApplied records the outcome of an action on a pod. If the pod is gone by now, the Event goes
+// to its controller instead, where the application team will see it.
+func (e *Experiment) podApplied(client kubernetes.Interface, pod v1.Pod, victim *Victim, err error) {
+	e.notifyApplied(victim, err)
+	if e.Recorder == nil {
+		return
+	}
+
+	var object runtime.Object = &pod
+
+	current, getErr := client.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+	if (errors.IsNotFound(getErr) || (getErr == nil && current.DeletionTimestamp != nil)) && ownerReference(pod) != nil {
+		object = ownerReference(pod)
+	}
+
+	e.record(object, victim.Action, err)
+}
+
+// nodeApplied records the outcome of an action on a node
+func (e *Experiment) nodeApplied(node *v1.Node, victim *Victim, err error) {
+	e.notifyApplied(victim, err)
+	if e.Recorder == nil {
+		return
+	}
+
+	e.record(node, victim.Action, err)
+}
+
+func (e *Experiment) notifyApplied(victim *Victim, err error) {
+	if err != nil {
+		e.notify(WebhookFailure, victim, err)
+	} else {
+		e.notify(WebhookSuccess, victim, nil)
+	}
+}
+
+func (e *Experiment) notify(event string, victim *Victim, err error) {
+	if e.Webhooks == nil {
+		return
+	}
+
+	// webhooks are delivered in the background, while the victim may still change
+	copied := *victim
+	payload := WebhookPayload{
+		Event:      event,
+		Time:       time.Now(),
+		Experiment: e.Name,
+		Instance:   e.Instance,
+		Action:     victim.Action,
+		Victim:     &copied,
+	}
+	if err != nil {
+		payload.Error = err.Error()
+	}
+	e.Webhooks.Notify(payload)
+}
+
+func (e *Experiment) record(object runtime.Object, action string, err error) {
+	if err != nil {
+		e.Recorder.Eventf(object, v1.EventTypeWarning, ReasonChaosFailed,
+			"%s failed in experiment %s by marmoset %s: %s", action, e.Name, e.Instance, err)
+		return
+	}
+	e.Recorder.Eventf(object, v1.EventTypeNormal, ReasonChaosApplied,
+		"%s in experiment %s by marmoset %s", action, e.Name, e.Instance)
+}
+
+// steadyStateViolated records that the steady state wasn't restored after an action. The Event goes
+// to the owner of a pod, as the pod itself is likely gone.
+func (e *Experiment) steadyStateViolated(victim *Victim, err error) {
+	if e.Recorder == nil {
+		return
+	}
+
+	e.Recorder.Eventf(victimReference(victim), v1.EventTypeWarning, ReasonSteadyStateViolated,
+		"steady state not restored after %s in experiment %s by marmoset %s: %s", victim.Action, e.Name, e.Instance, err)
+}
+
+// victimReference returns a reference to a victim, or to its owner if it has one
+func victimReference(victim *Victim) *v1.ObjectReference {
+	if victim.Kind == KindNode {
+		return &v1.ObjectReference{Kind: "Node", Name: victim.Name, UID: victim.UID}
+	}
+	if parts := strings.SplitN(victim.Owner, "/", 2); len(parts) == 2 {
+		return &v1.ObjectReference{Kind: parts[0], Namespace: victim.Namespace, Name: parts[1]}
+	}
+	return &v1.ObjectReference{Kind: "Pod", Namespace: victim.Namespace, Name: victim.Name, UID: victim.UID}
+}
+
+// ownerReference returns a reference to the controller of a pod, or nil if it has none
+func ownerReference(pod v1.Pod) *v1.ObjectReference {
+	owner := metav1.GetControllerOf(&pod)
+	if owner == nil {
+		return nil
+	}
+	return &v1.ObjectReference{
+		APIVersion: owner.APIVersion,
+		Kind:       owner.Kind,
+		Namespace:  pod.Namespace,
+		Name:       owner.Name,
+		UID:        owner.UID,
+	}
+}
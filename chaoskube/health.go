@@ -0,0 +1,170 @@
+package chaoskube
+
+import (
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/neo-technology/marmoset/chaoskube/action"
+)
+
+const (
+	// the reason of a waiting container that keeps crashing
+	reasonCrashLoopBackOff = "CrashLoopBackOff"
+	// how many offenders an exclusion names at most
+	maxNamedOffenders = 5
+)
+
+var (
+	// msgNodesNotReady is the log message when termination is suspended because too many nodes are NotReady
+	msgNodesNotReady = "too many nodes not ready"
+	// msgPodsCrashLooping is the log message when termination is suspended because too many pods are in CrashLoopBackOff
+	msgPodsCrashLooping = "too many pods crash looping"
+	// msgNodeCordoned is the log message when termination is suspended because a node is still cordoned by marmoset
+	msgNodeCordoned = "node cordoned by marmoset"
+	// msgPodsPending is the log message when termination is suspended because pods are pending for too long
+	msgPodsPending = "pods pending for too long"
+)
+
+// HealthGate suppresses chaos while the cluster is already degraded, as chaos then only makes
+// things worse and teaches nothing
+type HealthGate struct {
+	// chaos is suppressed while more nodes than this are NotReady; negative disables the check
+	MaxNotReadyNodes int `json:"maxNotReadyNodes"`
+	// chaos is suppressed while more pods than this are in CrashLoopBackOff in Namespaces;
+	// negative disables the check
+	MaxCrashLoopingPods int `json:"maxCrashLoopingPods"`
+	// the namespaces crash looping pods are counted in, the same as the namespaces of a PodChaosSpec
+	Namespaces labels.Selector `json:"-"`
+	// chaos is suppressed while a node is cordoned by a drain of marmoset, i.e. one is still in progress
+	// or failed to finish
+	CordonedNodes bool `json:"cordonedNodes"`
+	// chaos is suppressed while pods are pending for longer than this; zero disables the check
+	MaxPendingAge time.Duration `json:"maxPendingAge"`
+}
+
+// Check returns why chaos is suppressed, or nil if the cluster is healthy
+func (g *HealthGate) Check(client kubernetes.Interface, now time.Time) (*Exclusion, error) {
+	if g.MaxNotReadyNodes >= 0 || g.CordonedNodes {
+		nodeList, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if exclusion := g.checkNodes(nodeList.Items); exclusion != nil {
+			return exclusion, nil
+		}
+	}
+
+	if g.MaxCrashLoopingPods >= 0 || g.MaxPendingAge > 0 {
+		podList, err := client.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if exclusion, err := g.checkPods(podList.Items, now); exclusion != nil || err != nil {
+			return exclusion, err
+		}
+	}
+
+	return nil, nil
+}
+
+func (g *HealthGate) checkNodes(nodes []v1.Node) *Exclusion {
+	notReady := []string{}
+	cordoned := []string{}
+	for _, node := range nodes {
+		if !isNodeReady(node) {
+			notReady = append(notReady, node.Name)
+		}
+		if node.Labels[action.LabelMarmosetCordoned] == "true" {
+			cordoned = append(cordoned, node.Name)
+		}
+	}
+
+	if g.MaxNotReadyNodes >= 0 && len(notReady) > g.MaxNotReadyNodes {
+		return unhealthy(msgNodesNotReady, skipReasonNodesNotReady, notReady, g.MaxNotReadyNodes)
+	}
+	if g.CordonedNodes && len(cordoned) > 0 {
+		return unhealthy(msgNodeCordoned, skipReasonNodeCordoned, cordoned, 0)
+	}
+	return nil
+}
+
+func (g *HealthGate) checkPods(pods []v1.Pod, now time.Time) (*Exclusion, error) {
+	if g.MaxCrashLoopingPods >= 0 {
+		namespaces := g.Namespaces
+		if namespaces == nil {
+			namespaces = labels.Everything()
+		}
+		targeted, err := filterByNamespaces(pods, namespaces)
+		if err != nil {
+			return nil, err
+		}
+
+		crashLooping := []string{}
+		for _, pod := range targeted {
+			if isCrashLooping(pod) {
+				crashLooping = append(crashLooping, pod.Namespace+"/"+pod.Name)
+			}
+		}
+		if len(crashLooping) > g.MaxCrashLoopingPods {
+			return unhealthy(msgPodsCrashLooping, skipReasonPodsCrashLooping, crashLooping, g.MaxCrashLoopingPods), nil
+		}
+	}
+
+	if g.MaxPendingAge > 0 {
+		pending := []string{}
+		for _, pod := range filterByPhase(pods, v1.PodPending) {
+			if now.Sub(pod.CreationTimestamp.Time) > g.MaxPendingAge {
+				pending = append(pending, pod.Namespace+"/"+pod.Name)
+			}
+		}
+		if len(pending) > 0 {
+			return unhealthy(msgPodsPending, skipReasonPodsPending, pending, 0), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// unhealthy returns an exclusion naming the first few offenders
+func unhealthy(reason, skipReason string, offenders []string, max int) *Exclusion {
+	named := offenders
+	if len(named) > maxNamedOffenders {
+		named = append(named[:maxNamedOffenders:maxNamedOffenders], "...")
+	}
+	return &Exclusion{
+		Reason: reason,
+		Details: log.Fields{
+			"count":     len(offenders),
+			"max":       max,
+			"offenders": strings.Join(named, ", "),
+		},
+		skipReason: skipReason,
+	}
+}
+
+// isNodeReady tells whether a node has the Ready condition
+func isNodeReady(node v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// isCrashLooping tells whether one of the containers of a pod is in CrashLoopBackOff
+func isCrashLooping(pod v1.Pod) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting != nil && status.State.Waiting.Reason == reasonCrashLoopBackOff {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package chaoskube
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/neo-technology/marmoset/chaoskube/action"
+)
+
+func healthyNode(name string) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     v1.NodeStatus{Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}},
+	}
+}
+
+func (suite *Suite) TestHealthGate() {
+	now := ThankGodItsFriday{}.Now()
+
+	notReady := healthyNode("not-ready")
+	notReady.Status.Conditions[0].Status = v1.ConditionFalse
+
+	cordoned := healthyNode("cordoned")
+	cordoned.Labels = map[string]string{action.LabelMarmosetCordoned: "true"}
+
+	crashLooping := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "testing", Name: "crashing"},
+		Status: v1.PodStatus{
+			Phase: v1.PodRunning,
+			ContainerStatuses: []v1.ContainerStatus{
+				{State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: reasonCrashLoopBackOff}}},
+			},
+		},
+	}
+
+	pending := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "testing", Name: "pending", CreationTimestamp: metav1.NewTime(now.Add(-10 * time.Minute))},
+		Status:     v1.PodStatus{Phase: v1.PodPending},
+	}
+
+	everything := HealthGate{MaxNotReadyNodes: 0, MaxCrashLoopingPods: 0, CordonedNodes: true, MaxPendingAge: 5 * time.Minute}
+	nothing := HealthGate{MaxNotReadyNodes: -1, MaxCrashLoopingPods: -1}
+
+	otherNamespaces, err := labels.Parse("!testing")
+	suite.Require().NoError(err)
+	elsewhere := everything
+	elsewhere.Namespaces = otherNamespaces
+
+	lenient := everything
+	lenient.MaxNotReadyNodes = 1
+	lenient.MaxCrashLoopingPods = 1
+	lenient.MaxPendingAge = 15 * time.Minute
+
+	for _, tt := range []struct {
+		gate     HealthGate
+		objects  []runtime.Object
+		expected string
+	}{
+		{everything, []runtime.Object{healthyNode("healthy")}, ""},
+		{everything, []runtime.Object{healthyNode("healthy"), notReady}, msgNodesNotReady},
+		{everything, []runtime.Object{cordoned}, msgNodeCordoned},
+		{everything, []runtime.Object{crashLooping}, msgPodsCrashLooping},
+		{everything, []runtime.Object{pending}, msgPodsPending},
+		{elsewhere, []runtime.Object{crashLooping}, ""},
+		{lenient, []runtime.Object{notReady, crashLooping, pending}, ""},
+		{nothing, []runtime.Object{notReady, cordoned, crashLooping, pending}, ""},
+	} {
+		exclusion, err := tt.gate.Check(fake.NewSimpleClientset(tt.objects...), now)
+		suite.Require().NoError(err)
+		if tt.expected == "" {
+			suite.Nil(exclusion, "%+v", tt.gate)
+			continue
+		}
+		suite.Require().NotNil(exclusion, "%+v", tt.gate)
+		suite.Equal(tt.expected, exclusion.Reason)
+		suite.NotEmpty(exclusion.skipReason)
+		suite.Equal(1, exclusion.Details["count"])
+	}
+}
+
+// TestHealthGateSuppressesChaos tests that no chaos is attempted while the cluster is degraded
+func (suite *Suite) TestHealthGateSuppressesChaos() {
+	chaoskube := suite.setupOnFriday()
+	recorder := &chaosRecorder{}
+	chaoskube.Spec = recorder
+	chaoskube.HealthGate = &HealthGate{MaxNotReadyNodes: 0, MaxCrashLoopingPods: -1}
+
+	node := healthyNode("node1")
+	node.Status.Conditions[0].Status = v1.ConditionUnknown
+	_, err := chaoskube.Client.CoreV1().Nodes().Create(node)
+	suite.Require().NoError(err)
+
+	outcome, err := chaoskube.RunOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.False(recorder.invoked)
+	suite.Require().NotNil(outcome.Exclusion)
+	suite.Equal(msgNodesNotReady, outcome.Exclusion.Reason)
+	suite.assertLog(log.InfoLevel, msgNodesNotReady, log.Fields{"offenders": "node1"})
+}
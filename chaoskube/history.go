@@ -0,0 +1,281 @@
+package chaoskube
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// What came of a run
+	OutcomeApplied  = "applied"
+	OutcomeFailed   = "failed"
+	OutcomeExcluded = "excluded"
+	OutcomeNoVictim = "no victim"
+
+	// the key of the ConfigMap entry holding the history
+	historyKey = "history.jsonl"
+	// how often to retry writing the history ConfigMap when someone else changed it meanwhile
+	historyRetries = 5
+)
+
+// Record is the entry of one run in the History
+type Record struct {
+	// when the run started
+	Time       time.Time `json:"time"`
+	Experiment string    `json:"experiment,omitempty"`
+	Action     string    `json:"action,omitempty"`
+	// what chaos was imbued in, if anything
+	Victim *Victim `json:"victim,omitempty"`
+	// one of applied, failed, excluded or no victim
+	Outcome string `json:"outcome"`
+	// why the run failed, if it did
+	Error string `json:"error,omitempty"`
+	// how long the run took
+	DurationSeconds float64 `json:"durationSeconds"`
+	// the rule that skipped the run, if one did
+	Exclusion string `json:"exclusion,omitempty"`
+}
+
+// History is a durable record of runs, the audit trail of chaos
+type History interface {
+	// Append adds a record to the history
+	Append(record Record) error
+	// Query returns the records matching the query, oldest first
+	Query(query HistoryQuery) ([]Record, error)
+}
+
+// HistoryQuery selects records from a History. Zero values match everything.
+type HistoryQuery struct {
+	// only records at or after From
+	From time.Time
+	// only records before To
+	To time.Time
+	// only records of victims in this namespace
+	Namespace string
+	// only records of this action
+	Action string
+}
+
+// Matches tells whether a record is selected by the query
+func (q HistoryQuery) Matches(record Record) bool {
+	if !q.From.IsZero() && record.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !record.Time.Before(q.To) {
+		return false
+	}
+	if q.Namespace != "" && (record.Victim == nil || record.Victim.Namespace != q.Namespace) {
+		return false
+	}
+	if q.Action != "" && record.Action != q.Action {
+		return false
+	}
+	return true
+}
+
+// ParseHistoryQuery parses a query from its textual form. From and to may be given as RFC 3339
+// timestamps or as durations, meaning that long before now.
+func ParseHistoryQuery(from, to, namespace, action string, now time.Time) (HistoryQuery, error) {
+	query := HistoryQuery{Namespace: namespace, Action: action}
+
+	var err error
+	if query.From, err = parseQueryTime(from, now); err != nil {
+		return query, err
+	}
+	if query.To, err = parseQueryTime(to, now); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+func parseQueryTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if ago, err := time.ParseDuration(value); err == nil {
+		return now.Add(-ago), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid time '%v': must be RFC 3339 or a duration", value)
+	}
+	return t, nil
+}
+
+// NewRecords turns the outcome of a run into Records, one per victim, or a single one if there
+// was no victim
+func NewRecords(outcome Outcome, experiment, action string, duration time.Duration) []Record {
+	record := Record{
+		Time:            outcome.Time,
+		Experiment:      experiment,
+		Action:          action,
+		Error:           outcome.Error,
+		DurationSeconds: duration.Seconds(),
+	}
+
+	if len(outcome.Victims) == 0 {
+		switch {
+		case outcome.Error != "":
+			record.Outcome = OutcomeFailed
+		case outcome.Exclusion != nil:
+			record.Outcome = OutcomeExcluded
+			record.Exclusion = outcome.Exclusion.Reason
+		default:
+			record.Outcome = OutcomeNoVictim
+		}
+		return []Record{record}
+	}
+
+	records := make([]Record, 0, len(outcome.Victims))
+	for _, victim := range outcome.Victims {
+		record.Victim = victim
+		record.Action = victim.Action
+		record.Error = victim.Error
+		record.Outcome = OutcomeApplied
+		if victim.Error != "" {
+			record.Outcome = OutcomeFailed
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// FileHistory appends records as JSON Lines to a file
+type FileHistory struct {
+	Path string
+
+	mutex sync.Mutex
+}
+
+func (h *FileHistory) Append(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	file, err := os.OpenFile(h.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (h *FileHistory) Query(query HistoryQuery) ([]Record, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	file, err := os.Open(h.Path)
+	if os.IsNotExist(err) {
+		return []Record{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readRecords(bufio.NewScanner(file), query, h.Path)
+}
+
+// ConfigMapHistory keeps the most recent records in a ConfigMap, as a ring buffer of JSON Lines
+type ConfigMapHistory struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Name      string
+	// how many records to keep
+	Size int
+}
+
+func (h *ConfigMapHistory) Append(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; ; i++ {
+		err = h.append(line)
+		if err == nil || !errors.IsConflict(err) || i >= historyRetries {
+			return err
+		}
+	}
+}
+
+func (h *ConfigMapHistory) append(line []byte) error {
+	configMaps := h.Client.CoreV1().ConfigMaps(h.Namespace)
+
+	configMap, err := configMaps.Get(h.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = configMaps.Create(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: h.Namespace, Name: h.Name},
+			Data:       map[string]string{historyKey: string(line) + "\n"},
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	lines := strings.SplitAfter(configMap.Data[historyKey], "\n")
+	if last := len(lines) - 1; lines[last] == "" {
+		lines = lines[:last]
+	}
+	lines = append(lines, string(line)+"\n")
+	if len(lines) > h.Size {
+		lines = lines[len(lines)-h.Size:]
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[historyKey] = strings.Join(lines, "")
+	_, err = configMaps.Update(configMap)
+	return err
+}
+
+func (h *ConfigMapHistory) Query(query HistoryQuery) ([]Record, error) {
+	configMap, err := h.Client.CoreV1().ConfigMaps(h.Namespace).Get(h.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return []Record{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	source := fmt.Sprintf("configmap %s/%s", h.Namespace, h.Name)
+	return readRecords(bufio.NewScanner(bytes.NewBufferString(configMap.Data[historyKey])), query, source)
+}
+
+func readRecords(scanner *bufio.Scanner, query HistoryQuery, source string) ([]Record, error) {
+	records := []Record{}
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		record := Record{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s, line %d: %s", source, line, err)
+		}
+		if query.Matches(record) {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
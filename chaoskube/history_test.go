@@ -0,0 +1,202 @@
+package chaoskube
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/neo-technology/marmoset/util"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func (suite *Suite) TestHistoryQueryMatches() {
+	friday := ThankGodItsFriday{}.Now()
+	record := Record{
+		Time:   friday,
+		Action: "terminate pod",
+		Victim: &Victim{Kind: KindPod, Namespace: "testing", Name: "foo"},
+	}
+
+	for _, tt := range []struct {
+		query    HistoryQuery
+		expected bool
+	}{
+		{HistoryQuery{}, true},
+		{HistoryQuery{From: friday}, true},
+		{HistoryQuery{From: friday.Add(time.Second)}, false},
+		{HistoryQuery{To: friday}, false},
+		{HistoryQuery{To: friday.Add(time.Second)}, true},
+		{HistoryQuery{Namespace: "testing"}, true},
+		{HistoryQuery{Namespace: "default"}, false},
+		{HistoryQuery{Action: "terminate pod"}, true},
+		{HistoryQuery{Action: "drain node"}, false},
+	} {
+		suite.Equal(tt.expected, tt.query.Matches(record), "%+v", tt.query)
+	}
+
+	// records without a victim have no namespace
+	suite.False(HistoryQuery{Namespace: "testing"}.Matches(Record{Time: friday}))
+}
+
+func (suite *Suite) TestParseHistoryQuery() {
+	friday := ThankGodItsFriday{}.Now()
+
+	query, err := ParseHistoryQuery("24h", "1869-09-24T12:00:00Z", "testing", "terminate pod", friday)
+	suite.Require().NoError(err)
+	suite.Equal(friday.Add(-24*time.Hour), query.From)
+	suite.Equal(time.Date(1869, 9, 24, 12, 0, 0, 0, time.UTC), query.To)
+	suite.Equal("testing", query.Namespace)
+	suite.Equal("terminate pod", query.Action)
+
+	_, err = ParseHistoryQuery("yesterday", "", "", "", friday)
+	suite.Error(err)
+}
+
+func (suite *Suite) TestNewRecords() {
+	friday := ThankGodItsFriday{}.Now()
+	victim := &Victim{Kind: KindPod, Name: "foo", Action: "terminate pod"}
+	failed := &Victim{Kind: KindPod, Name: "bar", Action: "terminate pod", Error: "boom"}
+
+	for _, tt := range []struct {
+		outcome   Outcome
+		expected  string
+		exclusion string
+	}{
+		{Outcome{Time: friday, Victims: []*Victim{victim}}, OutcomeApplied, ""},
+		{Outcome{Time: friday, Victims: []*Victim{failed}, Error: "boom"}, OutcomeFailed, ""},
+		{Outcome{Time: friday, Error: "boom"}, OutcomeFailed, ""},
+		{Outcome{Time: friday, Exclusion: excluded(msgWeekdayExcluded, nil)}, OutcomeExcluded, msgWeekdayExcluded},
+		{Outcome{Time: friday}, OutcomeNoVictim, ""},
+	} {
+		records := NewRecords(tt.outcome, "experiment", "terminate pod", 2*time.Second)
+		suite.Require().Len(records, 1)
+		record := records[0]
+		suite.Equal(tt.expected, record.Outcome)
+		suite.Equal(tt.exclusion, record.Exclusion)
+		suite.Equal("experiment", record.Experiment)
+		suite.Equal("terminate pod", record.Action)
+		suite.Equal(2.0, record.DurationSeconds)
+	}
+
+	records := NewRecords(Outcome{Time: friday, Victims: []*Victim{victim, failed}, Error: "1 of 2 victims failed"},
+		"experiment", "terminate pod", 2*time.Second)
+	suite.Require().Len(records, 2)
+	suite.Equal(OutcomeApplied, records[0].Outcome)
+	suite.Equal("", records[0].Error)
+	suite.Equal(victim, records[0].Victim)
+	suite.Equal(OutcomeFailed, records[1].Outcome)
+	suite.Equal("boom", records[1].Error)
+	suite.Equal(failed, records[1].Victim)
+}
+
+func (suite *Suite) TestFileHistory() {
+	dir, err := ioutil.TempDir("", "history")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(dir)
+
+	history := &FileHistory{Path: filepath.Join(dir, "history.jsonl")}
+	suite.assertHistory(history)
+}
+
+func (suite *Suite) TestConfigMapHistory() {
+	history := &ConfigMapHistory{Client: fake.NewSimpleClientset(), Namespace: "marmoset", Name: "history", Size: 2}
+	suite.assertHistory(history)
+
+	// only the most recent records are kept
+	friday := ThankGodItsFriday{}.Now()
+	suite.Require().NoError(history.Append(Record{Time: friday.Add(1 * time.Hour)}))
+
+	records, err := history.Query(HistoryQuery{})
+	suite.Require().NoError(err)
+	suite.Require().Len(records, 2)
+	suite.Equal(friday.Add(1*time.Minute), records[0].Time)
+	suite.Equal(friday.Add(1*time.Hour), records[1].Time)
+}
+
+// assertHistory tests a History that keeps at least two records
+func (suite *Suite) assertHistory(history History) {
+	friday := ThankGodItsFriday{}.Now()
+
+	records, err := history.Query(HistoryQuery{})
+	suite.Require().NoError(err)
+	suite.Empty(records)
+
+	first := Record{Time: friday, Action: "terminate pod", Outcome: OutcomeApplied,
+		Victim: &Victim{Kind: KindPod, Namespace: "testing", Name: "foo", UID: "1234", Owner: "ReplicaSet/foo", Node: "node1"}}
+	second := Record{Time: friday.Add(1 * time.Minute), Action: "terminate pod", Outcome: OutcomeExcluded, Exclusion: msgWeekdayExcluded}
+	suite.Require().NoError(history.Append(first))
+	suite.Require().NoError(history.Append(second))
+
+	records, err = history.Query(HistoryQuery{})
+	suite.Require().NoError(err)
+	suite.Equal([]Record{first, second}, records)
+
+	records, err = history.Query(HistoryQuery{Namespace: "testing"})
+	suite.Require().NoError(err)
+	suite.Equal([]Record{first}, records)
+}
+
+// TestRunOnceWritesHistory tests that every run ends up in the history
+func (suite *Suite) TestRunOnceWritesHistory() {
+	chaoskube := suite.setupWithPods(
+		labels.Everything(),
+		labels.Everything(),
+		labels.Everything(),
+		[]time.Weekday{},
+		[]util.TimePeriod{},
+		[]time.Time{},
+		time.UTC,
+		time.Duration(0),
+		true,
+	)
+	chaoskube.Now = ThankGodItsFriday{}.Now
+	chaoskube.Experiment = &Experiment{Name: "experiment"}
+	chaoskube.History = &ConfigMapHistory{Client: chaoskube.Client, Namespace: "marmoset", Name: "history", Size: 10}
+
+	suite.Require().NoError(chaoskube.TerminateVictim(context.Background()))
+	chaoskube.ExcludedWeekdays = []time.Weekday{time.Friday}
+	suite.Require().NoError(chaoskube.TerminateVictim(context.Background()))
+
+	records, err := chaoskube.History.Query(HistoryQuery{})
+	suite.Require().NoError(err)
+	suite.Require().Len(records, 2)
+
+	suite.Equal(OutcomeApplied, records[0].Outcome)
+	suite.Equal("experiment", records[0].Experiment)
+	suite.Equal("dry run", records[0].Action)
+	suite.Require().NotNil(records[0].Victim)
+	suite.Equal(KindPod, records[0].Victim.Kind)
+
+	suite.Equal(OutcomeExcluded, records[1].Outcome)
+	suite.Equal(msgWeekdayExcluded, records[1].Exclusion)
+	suite.Equal("dry run", records[1].Action)
+}
+
+func (suite *Suite) TestAPIHistory() {
+	chaoskube := suite.setupOnFriday()
+	chaoskube.Spec = &chaosRecorder{}
+	chaoskube.History = &ConfigMapHistory{Client: chaoskube.Client, Namespace: "marmoset", Name: "history", Size: 10}
+	api := NewAPI(chaoskube, nil, testToken, logger)
+
+	suite.Require().NoError(chaoskube.TerminateVictim(context.Background()))
+
+	response := suite.request(api, http.MethodGet, "/history?from=1h", "Bearer "+testToken)
+	suite.Require().Equal(http.StatusOK, response.Code)
+	records := []Record{}
+	suite.Require().NoError(json.Unmarshal(response.Body.Bytes(), &records))
+	suite.Len(records, 1)
+
+	response = suite.request(api, http.MethodGet, "/history?to=1h", "Bearer "+testToken)
+	suite.Require().Equal(http.StatusOK, response.Code)
+	suite.Require().NoError(json.Unmarshal(response.Body.Bytes(), &records))
+	suite.Len(records, 0)
+
+	response = suite.request(api, http.MethodGet, "/history?from=soon", "Bearer "+testToken)
+	suite.Equal(http.StatusBadRequest, response.Code)
+}
@@ -0,0 +1,86 @@
+package chaoskube
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// Keys of the kill switch ConfigMap; the same settings can be made as annotations on
+	// marmoset's own namespace, prefixed with "marmoset/"
+	KeyPaused      = "paused"
+	KeyPausedUntil = "paused-until"
+	KeyPauseReason = "pause-reason"
+
+	AnnotationPaused      = "marmoset/" + KeyPaused
+	AnnotationPausedUntil = "marmoset/" + KeyPausedUntil
+	AnnotationPauseReason = "marmoset/" + KeyPauseReason
+)
+
+// KillSwitch stops all chaos while a well-known ConfigMap, or an annotation on marmoset's own
+// namespace, says so. It is checked before every run, so a pause takes effect within one tick.
+type KillSwitch struct {
+	// the namespace marmoset runs in, holding the ConfigMap
+	Namespace string `json:"namespace"`
+	// the name of the kill switch ConfigMap; empty to only check the namespace annotations
+	ConfigMap string `json:"configMap,omitempty"`
+}
+
+// Pause describes why and until when chaos is paused
+type Pause struct {
+	// when the pause ends by itself; zero if it doesn't
+	Until time.Time
+	// why chaos is paused, as given by whoever paused it
+	Reason string
+	// where the pause was found
+	Source string
+}
+
+// Check returns the pause in effect at the given point in time, or nil if chaos may go on.
+// A paused-until time in the past means the pause has expired.
+func (k *KillSwitch) Check(client kubernetes.Interface, now time.Time) (*Pause, error) {
+	if k.ConfigMap != "" {
+		configMap, err := client.CoreV1().ConfigMaps(k.Namespace).Get(k.ConfigMap, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		if err == nil {
+			source := fmt.Sprintf("configmap %s/%s", k.Namespace, k.ConfigMap)
+			pause, err := parsePause(configMap.Data, KeyPaused, KeyPausedUntil, KeyPauseReason, source, now)
+			if pause != nil || err != nil {
+				return pause, err
+			}
+		}
+	}
+
+	namespace, err := client.CoreV1().Namespaces().Get(k.Namespace, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	source := fmt.Sprintf("namespace %s", k.Namespace)
+	return parsePause(namespace.Annotations, AnnotationPaused, AnnotationPausedUntil, AnnotationPauseReason, source, now)
+}
+
+func parsePause(settings map[string]string, pausedKey, untilKey, reasonKey, source string, now time.Time) (*Pause, error) {
+	if !strings.EqualFold(strings.TrimSpace(settings[pausedKey]), "true") {
+		return nil, nil
+	}
+
+	pause := &Pause{Reason: settings[reasonKey], Source: source}
+	if until := strings.TrimSpace(settings[untilKey]); until != "" {
+		var err error
+		if pause.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			// an unreadable expiry keeps the pause in place rather than ending it early
+			return pause, fmt.Errorf("%s: invalid %s '%s', must be RFC 3339", source, untilKey, until)
+		}
+		if !now.Before(pause.Until) {
+			return nil, nil
+		}
+	}
+	return pause, nil
+}
@@ -0,0 +1,146 @@
+package chaoskube
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func (suite *Suite) TestKillSwitchCheck() {
+	now := ThankGodItsFriday{}.Now()
+	later := now.Add(1 * time.Hour).Format(time.RFC3339)
+	earlier := now.Add(-1 * time.Hour).Format(time.RFC3339)
+
+	for _, tt := range []struct {
+		name          string
+		configMapData map[string]string
+		annotations   map[string]string
+		expected      *Pause
+		expectError   bool
+	}{
+		{
+			name:     "nothing set",
+			expected: nil,
+		},
+		{
+			name:          "paused by configmap",
+			configMapData: map[string]string{KeyPaused: "true", KeyPauseReason: "incident 42"},
+			expected:      &Pause{Reason: "incident 42", Source: "configmap marmoset/marmoset-kill-switch"},
+		},
+		{
+			name:          "configmap says not paused",
+			configMapData: map[string]string{KeyPaused: "false", KeyPauseReason: "incident 42"},
+			expected:      nil,
+		},
+		{
+			name:          "paused until later",
+			configMapData: map[string]string{KeyPaused: "true", KeyPausedUntil: later},
+			expected:      &Pause{Until: now.Add(1 * time.Hour), Source: "configmap marmoset/marmoset-kill-switch"},
+		},
+		{
+			name:          "pause expired",
+			configMapData: map[string]string{KeyPaused: "true", KeyPausedUntil: earlier},
+			expected:      nil,
+		},
+		{
+			name:          "unreadable expiry stays paused",
+			configMapData: map[string]string{KeyPaused: "true", KeyPausedUntil: "tomorrow"},
+			expected:      &Pause{Source: "configmap marmoset/marmoset-kill-switch"},
+			expectError:   true,
+		},
+		{
+			name:        "paused by namespace annotation",
+			annotations: map[string]string{AnnotationPaused: "true", AnnotationPauseReason: "game day"},
+			expected:    &Pause{Reason: "game day", Source: "namespace marmoset"},
+		},
+		{
+			name:          "namespace annotation pauses even if the configmap doesn't",
+			configMapData: map[string]string{KeyPaused: "false"},
+			annotations:   map[string]string{AnnotationPaused: "true"},
+			expected:      &Pause{Source: "namespace marmoset"},
+		},
+	} {
+		client := fake.NewSimpleClientset(killSwitchObjects(tt.configMapData, tt.annotations)...)
+		killSwitch := &KillSwitch{Namespace: "marmoset", ConfigMap: "marmoset-kill-switch"}
+
+		pause, err := killSwitch.Check(client, now)
+		if tt.expectError {
+			suite.Error(err, tt.name)
+		} else {
+			suite.NoError(err, tt.name)
+		}
+		suite.Equal(tt.expected, pause, tt.name)
+	}
+}
+
+// TestTerminateVictimPaused tests that the kill switch suspends chaos with a distinct log message and metric
+func (suite *Suite) TestTerminateVictimPaused() {
+	chaoskube := suite.setupOnFriday()
+	chaoskube.Client = fake.NewSimpleClientset(killSwitchObjects(
+		map[string]string{KeyPaused: "true", KeyPauseReason: "incident 42"}, nil)...)
+	chaoskube.KillSwitch = &KillSwitch{Namespace: "marmoset", ConfigMap: "marmoset-kill-switch"}
+	recorder := &chaosRecorder{}
+	chaoskube.Spec = recorder
+
+	before := skippedRunsCount(skipReasonPaused)
+
+	err := chaoskube.TerminateVictim(context.Background())
+	suite.Require().NoError(err)
+
+	suite.False(recorder.invoked)
+	suite.assertLog(log.InfoLevel, msgPaused, log.Fields{"reason": "incident 42"})
+	suite.Equal(before+1, skippedRunsCount(skipReasonPaused))
+	suite.Equal(1.0, pausedValue())
+
+	// once the kill switch is released, chaos is no longer paused, as is seen on the next check
+	chaoskube.Client = fake.NewSimpleClientset(killSwitchObjects(map[string]string{KeyPaused: "false"}, nil)...)
+	suite.Require().NoError(chaoskube.TerminateVictim(context.Background()))
+	suite.True(recorder.invoked)
+	suite.Equal(0.0, pausedValue())
+}
+
+// TestTerminateVictimKillSwitchUnavailable tests that chaos is suspended if the kill switch can't be checked
+func (suite *Suite) TestTerminateVictimKillSwitchUnavailable() {
+	chaoskube := suite.setupOnFriday()
+	chaoskube.KillSwitch = &KillSwitch{Namespace: "marmoset", ConfigMap: "marmoset-kill-switch"}
+	recorder := &chaosRecorder{}
+	chaoskube.Spec = recorder
+
+	// the namespace doesn't exist in the fake cluster
+	err := chaoskube.TerminateVictim(context.Background())
+	suite.Error(err)
+	suite.False(recorder.invoked)
+}
+
+func killSwitchObjects(configMapData, annotations map[string]string) []runtime.Object {
+	objects := []runtime.Object{
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "marmoset", Annotations: annotations}},
+	}
+	if configMapData != nil {
+		objects = append(objects, &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "marmoset", Name: "marmoset-kill-switch"},
+			Data:       configMapData,
+		})
+	}
+	return objects
+}
+
+func pausedValue() float64 {
+	metric := &dto.Metric{}
+	paused.Write(metric)
+	return metric.GetGauge().GetValue()
+}
+
+func skippedRunsCount(reason string) float64 {
+	metric := &dto.Metric{}
+	skippedRuns.WithLabelValues(reason).Write(metric)
+	return metric.GetCounter().GetValue()
+}
@@ -0,0 +1,70 @@
+package chaoskube
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// skippedRuns counts runs in which no chaos was attempted, by reason
+	skippedRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "marmoset",
+		Name:      "skipped_runs_total",
+		Help:      "The number of runs in which no chaos was attempted, by reason",
+	}, []string{"reason"})
+	// paused is 1 while chaos is paused, by the kill switch, through the API or after a probe failed
+	paused = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "marmoset",
+		Name:      "paused",
+		Help:      "Whether chaos is currently paused, by the kill switch, through the API or after a failed probe",
+	})
+	// probeFailures counts failed checks of the steady-state hypothesis, before or after an action
+	probeFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "marmoset",
+		Name:      "probe_failures_total",
+		Help:      "The number of failed checks of the steady state, before or after an action",
+	}, []string{"phase"})
+	// victimsTotal counts the victims chaos was imbued in, by action and whether it failed
+	victimsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "marmoset",
+		Name:      "victims_total",
+		Help:      "The number of victims chaos was imbued in, by action and outcome",
+	}, []string{"action", "outcome"})
+	// victimsPerRun observes how many victims each run that found any picked
+	victimsPerRun = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "marmoset",
+		Name:      "victims_per_run",
+		Help:      "The number of victims picked by runs that found candidates",
+		Buckets:   []float64{1, 2, 3, 5, 10, 20, 50, 100},
+	})
+	// recoverySeconds observes how long the owners of victims took to recover, by action
+	recoverySeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "marmoset",
+		Name:      "recovery_seconds",
+		Help:      "How long the owners of victims took to have as many Ready pods as they want again, by action",
+		Buckets:   []float64{1, 2, 5, 10, 20, 30, 60, 120, 300, 600},
+	}, []string{"action"})
+	// recoverySLOViolations counts victims whose owners recovered slower than the SLO, or not at all
+	recoverySLOViolations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "marmoset",
+		Name:      "recovery_slo_violations_total",
+		Help:      "The number of victims whose owners took longer to recover than the SLO allows, or didn't recover in time, by action",
+	}, []string{"action"})
+)
+
+const (
+	skipReasonPaused      = "paused"
+	skipReasonSteadyState = "steady state"
+	// the health gate
+	skipReasonNodesNotReady    = "nodes not ready"
+	skipReasonPodsCrashLooping = "pods crash looping"
+	skipReasonNodeCordoned     = "node cordoned"
+	skipReasonPodsPending      = "pods pending"
+
+	// the outcome label of the victims metric
+	victimOutcomeApplied = "applied"
+	victimOutcomeFailed  = "failed"
+)
+
+func init() {
+	prometheus.MustRegister(skippedRuns, paused, probeFailures, victimsTotal, victimsPerRun, recoverySeconds, recoverySLOViolations)
+}
@@ -0,0 +1,219 @@
+package chaoskube
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neo-technology/marmoset/chaoskube/action"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// VictimCount tells how many victims a run picks. The zero value picks one.
+type VictimCount struct {
+	// a fixed number of victims
+	Count int `json:"count,omitempty"`
+	// a percentage of the candidates or, with PerOwner, of the pods each owner wants; rounded
+	// down, but at least one
+	Percent  float64 `json:"percent,omitempty"`
+	PerOwner bool    `json:"perOwner,omitempty"`
+}
+
+// ParseVictimCount parses a victim count written as a number like 3, a percentage of the
+// candidates like 30%, or a percentage of the pods each owner wants like 30%/owner
+func ParseVictimCount(count string) (VictimCount, error) {
+	text := strings.TrimSpace(count)
+
+	perOwner := strings.HasSuffix(text, "%/owner")
+	if perOwner {
+		text = strings.TrimSuffix(text, "/owner")
+	}
+
+	if strings.HasSuffix(text, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(text, "%"), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return VictimCount{}, fmt.Errorf("Invalid victim count '%v': percentage must be over 0 and at most 100", count)
+		}
+		return VictimCount{Percent: percent, PerOwner: perOwner}, nil
+	}
+
+	number, err := strconv.Atoi(text)
+	if err != nil || number < 1 {
+		return VictimCount{}, fmt.Errorf("Invalid victim count '%v': must be a positive number, N%% or N%%/owner", count)
+	}
+	return VictimCount{Count: number}, nil
+}
+
+func (c VictimCount) String() string {
+	switch {
+	case c.Percent > 0 && c.PerOwner:
+		return strconv.FormatFloat(c.Percent, 'f', -1, 64) + "%/owner"
+	case c.Percent > 0:
+		return strconv.FormatFloat(c.Percent, 'f', -1, 64) + "%"
+	case c.Count > 0:
+		return strconv.Itoa(c.Count)
+	}
+	return "1"
+}
+
+// of returns how many victims to pick among the given number of candidates
+func (c VictimCount) of(candidates int) int {
+	if c.Percent > 0 {
+		count := int(float64(candidates) * c.Percent / 100)
+		if count < 1 {
+			return 1
+		}
+		return count
+	}
+	if c.Count > 0 {
+		return c.Count
+	}
+	return 1
+}
+
+// pickVictims returns the indices of the victims among the candidates, as many as the count
+// asks for, picked one by one by the selector. A candidate is only picked if admit agrees, which
+// lets the blast radius take the victims picked before into account; only then is a HitRecorder
+// told about it. A percentage per owner is of what replicas returns for the candidates of the
+// owner, or of their number if replicas is nil.
+func pickVictims(candidates []metav1.Object, count VictimCount, selector VictimSelector, admit func(i int) bool, replicas func(group []int) int, now time.Time) []int {
+	groups := [][]int{}
+	if count.PerOwner {
+		owners, byOwner := groupByOwner(candidates)
+		for _, owner := range owners {
+			groups = append(groups, byOwner[owner])
+		}
+	} else {
+		all := make([]int, len(candidates))
+		for i := range candidates {
+			all[i] = i
+		}
+		groups = append(groups, all)
+	}
+
+	picked := []int{}
+	for _, group := range groups {
+		total := len(group)
+		if count.PerOwner && replicas != nil {
+			total = replicas(group)
+		}
+		wanted := count.of(total)
+		remaining := append([]int{}, group...)
+		for wanted > 0 && len(remaining) > 0 {
+			objects := make([]metav1.Object, 0, len(remaining))
+			for _, i := range remaining {
+				objects = append(objects, candidates[i])
+			}
+
+			j := selectVictim(selector, objects, now)
+			i := remaining[j]
+			remaining = append(remaining[:j], remaining[j+1:]...)
+
+			if admit(i) {
+				picked = append(picked, i)
+				wanted--
+				if recorder, ok := selector.(HitRecorder); ok {
+					recorder.Hit(candidates[i], now)
+				}
+			}
+		}
+	}
+	return picked
+}
+
+// ownerReplicas returns how many pods the controller wants, or the given number of candidates if
+// there is no controller, it doesn't want a number of pods or it can't be asked
+func ownerReplicas(client kubernetes.Interface, namespace string, controller *metav1.OwnerReference, candidates int, logger log.FieldLogger) int {
+	if controller == nil || !(recoverable(controller.Kind) || controller.Kind == action.KindDeployment) {
+		return candidates
+	}
+	desired, _, err := desiredPods(client, namespace, controller)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"namespace": namespace,
+			"owner":     controller.Kind + "/" + controller.Name,
+			"err":       err,
+		}).Warn("failed to look up replicas, counting candidates instead")
+		return candidates
+	}
+	return int(desired)
+}
+
+// admitVictim tells whether the blast radius allows chaos in the victim and, if it does, strikes
+// it, so the victims picked after it in the same run count it
+func admitVictim(blastRadius *BlastRadius, victim *Victim, now time.Time) bool {
+	if blastRadius == nil {
+		return true
+	}
+	if !blastRadius.Allows(victim, now) {
+		return false
+	}
+	blastRadius.Strike(victim, now)
+	return true
+}
+
+// applyAll calls apply for each of n victims, at most concurrency at a time, and returns when all
+// are done
+func applyAll(n, concurrency int, apply func(i int)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			apply(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// victimsApplied records the outcome of applying chaos to each victim, in the victim, the log and
+// the metrics, and returns the error of the run
+func victimsApplied(logger log.FieldLogger, victims []*Victim, errs []error) error {
+	victimsPerRun.Observe(float64(len(victims)))
+	for i, victim := range victims {
+		if errs[i] != nil {
+			victim.Error = errs[i].Error()
+			victimsTotal.WithLabelValues(victim.Action, victimOutcomeFailed).Inc()
+			logger.WithFields(log.Fields{
+				"namespace": victim.Namespace,
+				"name":      victim.Name,
+				"err":       errs[i],
+			}).Error(victim.Action + " failed")
+			continue
+		}
+		victimsTotal.WithLabelValues(victim.Action, victimOutcomeApplied).Inc()
+	}
+	return victimsError(victims, errs)
+}
+
+// victimsError sums up the errors of applying chaos to the victims, or returns nil if there were
+// none. The error of a single victim is returned as is.
+func victimsError(victims []*Victim, errs []error) error {
+	failed := []string{}
+	var last error
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", victims[i], err))
+			last = err
+		}
+	}
+
+	switch {
+	case len(failed) == 0:
+		return nil
+	case len(victims) == 1:
+		return last
+	}
+	return fmt.Errorf("%d of %d victims failed: %s", len(failed), len(victims), strings.Join(failed, "; "))
+}
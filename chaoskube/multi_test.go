@@ -0,0 +1,301 @@
+package chaoskube
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/neo-technology/marmoset/chaoskube/action"
+	"github.com/neo-technology/marmoset/util"
+	log "github.com/sirupsen/logrus"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// failingPodAction fails on the pods named in failOn and counts how many pods it handles at a time
+type failingPodAction struct {
+	failOn map[string]bool
+
+	mutex      sync.Mutex
+	applied    []string
+	running    int32
+	maxRunning int32
+}
+
+func (a *failingPodAction) Init(ctx context.Context, k8sclient kubernetes.Interface) error {
+	return nil
+}
+
+func (a *failingPodAction) ApplyToPod(ctx context.Context, victim v1.Pod) error {
+	running := atomic.AddInt32(&a.running, 1)
+	defer atomic.AddInt32(&a.running, -1)
+
+	a.mutex.Lock()
+	if running > a.maxRunning {
+		a.maxRunning = running
+	}
+	a.applied = append(a.applied, victim.Name)
+	a.mutex.Unlock()
+
+	// give the other victims a chance to overlap
+	time.Sleep(10 * time.Millisecond)
+
+	if a.failOn[victim.Name] {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+func (a *failingPodAction) Name() string {
+	return "fail pod"
+}
+
+func runningPod(name, owner string) v1.Pod {
+	pod := ownedPod(name, owner, -time.Hour, nil)
+	pod.Status.Phase = v1.PodRunning
+	return pod
+}
+
+func (suite *Suite) TestParseVictimCount() {
+	for _, tt := range []struct {
+		text     string
+		expected VictimCount
+	}{
+		{"3", VictimCount{Count: 3}},
+		{"30%", VictimCount{Percent: 30}},
+		{"12.5%/owner", VictimCount{Percent: 12.5, PerOwner: true}},
+	} {
+		count, err := ParseVictimCount(tt.text)
+		suite.Require().NoError(err, tt.text)
+		suite.Equal(tt.expected, count)
+		suite.Equal(tt.text, count.String())
+	}
+	suite.Equal("1", VictimCount{}.String())
+
+	for _, invalid := range []string{"", "0", "-1", "many", "0%", "101%", "30%/team", "3/owner"} {
+		_, err := ParseVictimCount(invalid)
+		suite.Error(err, invalid)
+	}
+}
+
+func (suite *Suite) TestVictimCountOf() {
+	for _, tt := range []struct {
+		count      VictimCount
+		candidates int
+		expected   int
+	}{
+		{VictimCount{}, 10, 1},
+		{VictimCount{Count: 3}, 10, 3},
+		{VictimCount{Percent: 30}, 10, 3},
+		{VictimCount{Percent: 30}, 5, 1},
+		{VictimCount{Percent: 30}, 1, 1},
+		{VictimCount{Percent: 100}, 7, 7},
+	} {
+		suite.Equal(tt.expected, tt.count.of(tt.candidates), "%s of %d", tt.count, tt.candidates)
+	}
+}
+
+func (suite *Suite) TestPickVictims() {
+	friday := ThankGodItsFriday{}.Now()
+	pods := []v1.Pod{
+		runningPod("a1", "a"), runningPod("a2", "a"), runningPod("a3", "a"), runningPod("a4", "a"),
+		runningPod("b1", "b"), runningPod("b2", "b"),
+	}
+	admitAll := func(i int) bool { return true }
+	selector := &RandomSelector{Rand: rand.New(rand.NewSource(1))}
+
+	names := func(picked []int) []string {
+		result := []string{}
+		for _, i := range picked {
+			result = append(result, pods[i].Name)
+		}
+		return result
+	}
+
+	suite.Len(pickVictims(podObjects(pods), VictimCount{Count: 4}, selector, admitAll, nil, friday), 4)
+	suite.Len(pickVictims(podObjects(pods), VictimCount{Count: 10}, selector, admitAll, nil, friday), 6)
+	suite.Len(pickVictims(podObjects(pods), VictimCount{Percent: 50}, selector, admitAll, nil, friday), 3)
+
+	// half of each owner: 2 of a, 1 of b
+	picked := names(pickVictims(podObjects(pods), VictimCount{Percent: 50, PerOwner: true}, selector, admitAll, nil, friday))
+	suite.Len(picked, 3)
+	owners := map[byte]int{}
+	for _, name := range picked {
+		owners[name[0]]++
+	}
+	suite.Equal(map[byte]int{'a': 2, 'b': 1}, owners)
+
+	// candidates not admitted are passed over for others
+	admitB := func(i int) bool { return pods[i].Name[0] == 'b' }
+	suite.ElementsMatch([]string{"b1", "b2"}, names(pickVictims(podObjects(pods), VictimCount{Count: 3}, selector, admitB, nil, friday)))
+}
+
+// TestVictimsPerOwnerOfReplicas tests that a percentage per owner is of the pods the owner wants,
+// not of those left after filtering
+func (suite *Suite) TestVictimsPerOwnerOfReplicas() {
+	friday := ThankGodItsFriday{}.Now()
+	// the ReplicaSet wants 10 pods, of which only 3 are candidates
+	client := fake.NewSimpleClientset(webReplicaSet(10), webPod("web-1", ready), webPod("web-2", ready), webPod("web-3", ready))
+	spec := &PodChaosSpec{
+		Action:      action.NewDryRunPodAction(),
+		Count:       VictimCount{Percent: 30, PerOwner: true},
+		Labels:      labels.Everything(),
+		Annotations: labels.Everything(),
+		Namespaces:  labels.Everything(),
+		Logger:      logger,
+	}
+
+	victims, err := spec.Apply(context.Background(), client, friday)
+	suite.Require().NoError(err)
+	suite.Len(victims, 3)
+
+	// without a ReplicaSet to ask, the candidates are counted
+	client = fake.NewSimpleClientset(webPod("web-1", ready), webPod("web-2", ready), webPod("web-3", ready))
+	victims, err = spec.Apply(context.Background(), client, friday)
+	suite.Require().NoError(err)
+	suite.Len(victims, 1)
+}
+
+// TestVictimsPerOwnerOfDeployment tests that the pods of a Deployment in the middle of a rollout
+// have one owner, not one per ReplicaSet
+func (suite *Suite) TestVictimsPerOwnerOfDeployment() {
+	replicas := int32(10)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+	pods := []v1.Pod{ownerPod("old-1", "ReplicaSet", "web-old"), ownerPod("old-2", "ReplicaSet", "web-old"), ownerPod("new-1", "ReplicaSet", "web-new"), ownerPod("new-2", "ReplicaSet", "web-new")}
+	client := fake.NewSimpleClientset(deployment, replicaSet("web-old", "web"), replicaSet("web-new", "web"), &pods[0], &pods[1], &pods[2], &pods[3])
+	spec := &PodChaosSpec{
+		Action:      action.NewDryRunPodAction(),
+		Count:       VictimCount{Percent: 30, PerOwner: true},
+		Labels:      labels.Everything(),
+		Annotations: labels.Everything(),
+		Namespaces:  labels.Everything(),
+		Logger:      logger,
+	}
+
+	// 30% of the 10 pods the Deployment wants, rather than 1 of each ReplicaSet
+	victims, err := spec.Apply(context.Background(), client, ThankGodItsFriday{}.Now())
+	suite.Require().NoError(err)
+	suite.Len(victims, 3)
+}
+
+func (suite *Suite) TestApplyAll() {
+	var running, maxRunning, calls int32
+	var mutex sync.Mutex
+	applyAll(10, 3, func(i int) {
+		now := atomic.AddInt32(&running, 1)
+		mutex.Lock()
+		if now > maxRunning {
+			maxRunning = now
+		}
+		mutex.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&calls, 1)
+	})
+
+	suite.Equal(int32(10), calls)
+	suite.True(maxRunning <= 3)
+	suite.True(maxRunning > 1)
+}
+
+func (suite *Suite) TestPodChaosSpecMultipleVictims() {
+	friday := ThankGodItsFriday{}.Now()
+	pods := []v1.Pod{runningPod("a1", "a"), runningPod("a2", "a"), runningPod("a3", "a"), runningPod("b1", "b")}
+	client := fake.NewSimpleClientset(&pods[0], &pods[1], &pods[2], &pods[3])
+
+	action := &failingPodAction{failOn: map[string]bool{"a2": true}}
+	spec := &PodChaosSpec{
+		Action:      action,
+		Count:       VictimCount{Count: 4},
+		Concurrency: 2,
+		Labels:      labels.Everything(),
+		Annotations: labels.Everything(),
+		Namespaces:  labels.Everything(),
+		Logger:      logger,
+	}
+
+	victims, err := spec.Apply(context.Background(), client, friday)
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "1 of 4 victims failed")
+	suite.Contains(err.Error(), "boom")
+
+	suite.Len(victims, 4)
+	suite.ElementsMatch([]string{"a1", "a2", "a3", "b1"}, action.applied)
+	suite.Equal(int32(2), action.maxRunning)
+	for _, victim := range victims {
+		if victim.Name == "a2" {
+			suite.Equal("boom", victim.Error)
+		} else {
+			suite.Empty(victim.Error, victim.Name)
+		}
+	}
+	suite.assertLog(log.ErrorLevel, "fail pod failed", log.Fields{"namespace": "default", "name": "a2", "err": errors.New("boom")})
+}
+
+func (suite *Suite) TestPodChaosSpecMultipleVictimsBlastRadius() {
+	friday := ThankGodItsFriday{}.Now()
+	pods := []v1.Pod{runningPod("a1", "a"), runningPod("a2", "a"), runningPod("b1", "b"), runningPod("c1", "c")}
+	client := fake.NewSimpleClientset(&pods[0], &pods[1], &pods[2], &pods[3])
+
+	action := &failingPodAction{}
+	spec := &PodChaosSpec{
+		Action:      action,
+		Count:       VictimCount{Count: 4},
+		BlastRadius: &BlastRadius{Limits: []Limit{{Scope: ScopeOwner, Max: 1, Window: time.Hour}}},
+		Labels:      labels.Everything(),
+		Annotations: labels.Everything(),
+		Namespaces:  labels.Everything(),
+		Logger:      logger,
+	}
+
+	// the limit of one per owner holds among the victims of a single run too
+	victims, err := spec.Apply(context.Background(), client, friday)
+	suite.Require().NoError(err)
+	suite.Len(victims, 3)
+	suite.Len(action.applied, 3)
+	suite.Contains(action.applied, "b1")
+	suite.Contains(action.applied, "c1")
+}
+
+func (suite *Suite) TestRunOnceMultipleVictims() {
+	chaoskube := suite.setupWithPods(
+		labels.Everything(),
+		labels.Everything(),
+		labels.Everything(),
+		[]time.Weekday{},
+		[]util.TimePeriod{},
+		[]time.Time{},
+		time.UTC,
+		time.Duration(0),
+		true,
+	)
+	chaoskube.Now = ThankGodItsFriday{}.Now
+	spec := chaoskube.Spec.(*PodChaosSpec)
+	spec.Count = VictimCount{Percent: 100}
+
+	history := &ConfigMapHistory{Client: chaoskube.Client, Namespace: "marmoset", Name: "history", Size: 10}
+	chaoskube.History = history
+
+	outcome, err := chaoskube.RunOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.Len(outcome.Victims, 2)
+
+	records, err := history.Query(HistoryQuery{})
+	suite.Require().NoError(err)
+	suite.Require().Len(records, 2)
+	for _, record := range records {
+		suite.Equal(OutcomeApplied, record.Outcome)
+		suite.NotNil(record.Victim)
+	}
+}
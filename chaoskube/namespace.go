@@ -0,0 +1,90 @@
+package chaoskube
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NamespaceCache looks up namespaces and keeps them for a while, so filtering pods by their
+// namespace doesn't cost a request per pod
+type NamespaceCache struct {
+	// how long a namespace is kept; zero keeps it forever
+	TTL time.Duration
+	// looks namespaces up in memory instead; optional
+	Cluster *ClusterCache
+
+	// guards namespaces
+	mutex      sync.Mutex
+	namespaces map[string]cachedNamespace
+}
+
+type cachedNamespace struct {
+	// nil if there is no such namespace
+	namespace *v1.Namespace
+	fetched   time.Time
+}
+
+// NewNamespaceCache returns a cache keeping namespaces for the given time
+func NewNamespaceCache(ttl time.Duration) *NamespaceCache {
+	return &NamespaceCache{TTL: ttl}
+}
+
+// Get returns the namespace of the given name, or nil if there is none
+func (c *NamespaceCache) Get(client kubernetes.Interface, name string, now time.Time) (*v1.Namespace, error) {
+	if c.Cluster != nil {
+		return c.Cluster.Namespace(name)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if cached, ok := c.namespaces[name]; ok && (c.TTL == 0 || now.Sub(cached.fetched) < c.TTL) {
+		return cached.namespace, nil
+	}
+
+	namespace, err := client.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		namespace, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.namespaces == nil {
+		c.namespaces = map[string]cachedNamespace{}
+	}
+	c.namespaces[name] = cachedNamespace{namespace: namespace, fetched: now}
+	return namespace, nil
+}
+
+// filterByNamespaceLabels filters a list of pods by the labels of their namespaces. Pods in
+// namespaces that don't exist (anymore) have no namespace labels.
+func filterByNamespaceLabels(client kubernetes.Interface, pods []v1.Pod, selector labels.Selector, cache *NamespaceCache, now time.Time) ([]v1.Pod, error) {
+	// empty filter returns original list
+	if selector.Empty() {
+		return pods, nil
+	}
+
+	filteredList := []v1.Pod{}
+	for _, pod := range pods {
+		namespace, err := cache.Get(client, pod.Namespace, now)
+		if err != nil {
+			return nil, err
+		}
+
+		namespaceLabels := labels.Set{}
+		if namespace != nil {
+			namespaceLabels = labels.Set(namespace.Labels)
+		}
+		if selector.Matches(namespaceLabels) {
+			filteredList = append(filteredList, pod)
+		}
+	}
+	return filteredList, nil
+}
@@ -0,0 +1,108 @@
+package chaoskube
+
+import (
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func labelledNamespace(name string, namespaceLabels map[string]string) *v1.Namespace {
+	return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: namespaceLabels}}
+}
+
+func (suite *Suite) TestNamespaceCache() {
+	friday := ThankGodItsFriday{}.Now()
+	client := fake.NewSimpleClientset(labelledNamespace("graph", nil))
+	cache := NewNamespaceCache(time.Minute)
+
+	namespace, err := cache.Get(client, "graph", friday)
+	suite.Require().NoError(err)
+	suite.Require().NotNil(namespace)
+	suite.Equal("graph", namespace.Name)
+
+	namespace, err = cache.Get(client, "missing", friday)
+	suite.Require().NoError(err)
+	suite.Nil(namespace)
+
+	// both are cached, found or not
+	cache.Get(client, "graph", friday.Add(30*time.Second))
+	cache.Get(client, "missing", friday.Add(30*time.Second))
+	suite.Len(client.Actions(), 2)
+
+	// until they expire
+	cache.Get(client, "graph", friday.Add(time.Minute))
+	suite.Len(client.Actions(), 3)
+}
+
+func (suite *Suite) TestFilterByNamespaceLabels() {
+	friday := ThankGodItsFriday{}.Now()
+	client := fake.NewSimpleClientset(
+		labelledNamespace("graph-staging", map[string]string{"team": "graph", "env": "staging"}),
+		labelledNamespace("graph-prod", map[string]string{"team": "graph", "env": "prod"}),
+		labelledNamespace("web", map[string]string{"team": "web"}),
+	)
+	pods := []v1.Pod{
+		annotatedPod("graph-staging", "foo", nil),
+		annotatedPod("graph-prod", "bar", nil),
+		annotatedPod("web", "baz", nil),
+		annotatedPod("gone", "qux", nil),
+	}
+
+	for _, tt := range []struct {
+		selector string
+		expected []string
+	}{
+		{"", []string{"foo", "bar", "baz", "qux"}},
+		{"team=graph,env=staging", []string{"foo"}},
+		{"team in (graph, web),env!=prod", []string{"foo", "baz"}},
+		{"!env", []string{"baz", "qux"}},
+		{"team notin (web)", []string{"foo", "bar", "qux"}},
+	} {
+		selector, err := labels.Parse(tt.selector)
+		suite.Require().NoError(err)
+
+		filtered, err := filterByNamespaceLabels(client, pods, selector, &NamespaceCache{}, friday)
+		suite.Require().NoError(err)
+
+		names := []string{}
+		for _, pod := range filtered {
+			names = append(names, pod.Name)
+		}
+		suite.Equal(tt.expected, names, tt.selector)
+	}
+}
+
+// TestPodChaosSpecNamespaceLabels tests that namespace labels combine with namespace names
+func (suite *Suite) TestPodChaosSpecNamespaceLabels() {
+	friday := ThankGodItsFriday{}.Now()
+	client := fake.NewSimpleClientset(
+		labelledNamespace("graph-staging", map[string]string{"team": "graph"}),
+		labelledNamespace("graph-prod", map[string]string{"team": "graph"}),
+	)
+	for _, pod := range []v1.Pod{annotatedPod("graph-staging", "foo", nil), annotatedPod("graph-prod", "bar", nil)} {
+		_, err := client.CoreV1().Pods(pod.Namespace).Create(&pod)
+		suite.Require().NoError(err)
+	}
+
+	namespaces, err := labels.Parse("!graph-prod")
+	suite.Require().NoError(err)
+	namespaceLabels, err := labels.Parse("team=graph")
+	suite.Require().NoError(err)
+
+	spec := &PodChaosSpec{
+		Labels:          labels.Everything(),
+		Annotations:     labels.Everything(),
+		Namespaces:      namespaces,
+		NamespaceLabels: namespaceLabels,
+		Logger:          logger,
+	}
+	pods, report, err := spec.candidates(client, friday)
+	suite.Require().NoError(err)
+	suite.Require().Len(pods, 1)
+	suite.Equal("foo", pods[0].Name)
+	suite.Contains(report.Filters, FilterCount{Filter: FilterNamespaceLabels, Removed: 0})
+	suite.Contains(spec.reason(pods[0], friday), "namespace labels match 'team=graph'")
+}
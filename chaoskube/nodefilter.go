@@ -0,0 +1,60 @@
+package chaoskube
+
+import (
+	"fmt"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+// nodes returns the names of the nodes the pods to choose from must run on, or nil if they may run
+// anywhere. Nodes are looked up by their labels, if there is a selector for them.
+func (s *PodChaosSpec) nodes(client clientset.Interface) ([]string, error) {
+	if s.NodeLabels == nil || s.NodeLabels.Empty() {
+		if len(s.NodeNames) == 0 {
+			return nil, nil
+		}
+		return s.NodeNames, nil
+	}
+
+	listed, err := s.listNodes(client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up nodes: %s", err)
+	}
+
+	nodes := []string{}
+	for _, node := range listed {
+		if (len(s.NodeNames) == 0 || contains(s.NodeNames, node.Name)) && s.NodeLabels.Matches(labels.Set(node.Labels)) {
+			nodes = append(nodes, node.Name)
+		}
+	}
+	return nodes, nil
+}
+
+// listNodes lists the nodes matching the node selector, from the cache if there is one
+func (s *PodChaosSpec) listNodes(client clientset.Interface) ([]v1.Node, error) {
+	if s.Cache != nil {
+		return s.Cache.Nodes(s.NodeLabels)
+	}
+
+	nodeList, err := client.CoreV1().Nodes().List(metav1.ListOptions{LabelSelector: s.NodeLabels.String()})
+	if err != nil {
+		return nil, err
+	}
+	return nodeList.Items, nil
+}
+
+// filterByNodes filters a list of pods by the names of the nodes they run on.
+func filterByNodes(pods []v1.Pod, nodes []string) []v1.Pod {
+	filteredList := []v1.Pod{}
+
+	for _, pod := range pods {
+		if contains(nodes, pod.Spec.NodeName) {
+			filteredList = append(filteredList, pod)
+		}
+	}
+
+	return filteredList
+}
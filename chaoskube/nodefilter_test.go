@@ -0,0 +1,84 @@
+package chaoskube
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func labelledNode(name string, nodeLabels map[string]string) *v1.Node {
+	return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nodeLabels}}
+}
+
+func podOnNode(name, node string) *v1.Pod {
+	pod := statusPod(name)
+	pod.Spec.NodeName = node
+	return &pod
+}
+
+// podListFieldSelector returns the field selector of the last list of pods
+func podListFieldSelector(client *fake.Clientset) string {
+	selector := ""
+	for _, action := range client.Actions() {
+		if list, ok := action.(k8stesting.ListAction); ok && action.GetResource().Resource == "pods" {
+			selector = list.GetListRestrictions().Fields.String()
+		}
+	}
+	return selector
+}
+
+func (suite *Suite) TestPodChaosSpecNodes() {
+	friday := ThankGodItsFriday{}.Now()
+
+	for _, tt := range []struct {
+		nodeNames      []string
+		nodeLabels     string
+		expected       []string
+		fieldSelector  string
+		expectedReason string
+	}{
+		{nil, "", []string{"foo", "bar", "baz", "qux"}, "", "phase is Running"},
+		{[]string{"spot-1"}, "", []string{"foo"}, "spec.nodeName=spot-1", "node spot-1 is one of spot-1"},
+		{[]string{"spot-1", "regular-1"}, "", []string{"foo", "baz"}, "", "is one of spot-1,regular-1"},
+		{nil, "pool=spot", []string{"foo", "bar"}, "", "match 'pool=spot'"},
+		{nil, "pool=spot,zone=a", []string{"foo"}, "spec.nodeName=spot-1", "labels of node spot-1 match"},
+		{[]string{"spot-2", "regular-1"}, "pool=spot", []string{"bar"}, "spec.nodeName=spot-2", "node spot-2 is one of"},
+		{nil, "pool=gpu", []string{}, "", ""},
+	} {
+		client := fake.NewSimpleClientset(
+			labelledNode("spot-1", map[string]string{"pool": "spot", "zone": "a"}),
+			labelledNode("spot-2", map[string]string{"pool": "spot", "zone": "b"}),
+			labelledNode("regular-1", map[string]string{"pool": "regular", "zone": "a"}),
+			podOnNode("foo", "spot-1"),
+			podOnNode("bar", "spot-2"),
+			podOnNode("baz", "regular-1"),
+			podOnNode("qux", "regular-2"),
+		)
+		nodeLabels, err := labels.Parse(tt.nodeLabels)
+		suite.Require().NoError(err)
+
+		spec := &PodChaosSpec{
+			NodeNames:   tt.nodeNames,
+			NodeLabels:  nodeLabels,
+			Labels:      labels.Everything(),
+			Annotations: labels.Everything(),
+			Namespaces:  labels.Everything(),
+			Logger:      logger,
+		}
+
+		report, err := spec.Candidates(client, friday)
+		suite.Require().NoError(err)
+
+		names := []string{}
+		for _, candidate := range report.Candidates {
+			names = append(names, candidate.Name)
+		}
+		suite.ElementsMatch(tt.expected, names, tt.nodeLabels)
+		suite.Equal(tt.fieldSelector, podListFieldSelector(client), tt.nodeLabels)
+		if len(report.Candidates) > 0 {
+			suite.Contains(report.Candidates[0].Reason, tt.expectedReason)
+		}
+	}
+}
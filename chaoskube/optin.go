@@ -0,0 +1,139 @@
+package chaoskube
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// Annotations of pods and namespaces steering opt-in mode
+	AnnotationEnabled   = "marmoset/enabled"
+	AnnotationDisabled  = "marmoset/disabled"
+	AnnotationMaxPerDay = "marmoset/max-per-day"
+	AnnotationActions   = "marmoset/actions"
+
+	// the window of AnnotationMaxPerDay
+	day = 24 * time.Hour
+)
+
+// OptIn restricts chaos to pods whose teams asked for it, by annotating the pod or its namespace
+// with marmoset/enabled=true. Annotations of the pod take precedence over those of its namespace:
+// marmoset/max-per-day caps the victims per day among the pods of the owner, or of the namespace,
+// and marmoset/actions lists the actions allowed, e.g. delete-pod,exec-pod. Whatever else it says,
+// marmoset/disabled=true on either one keeps chaos away.
+type OptIn struct {
+	// the name the action is configured by, matched against marmoset/actions
+	Action string
+}
+
+// optInSettings is what the annotations of a pod and its namespace say about it
+type optInSettings struct {
+	enabled  bool
+	disabled bool
+	// the maximum number of victims per day, and what they are counted by; negative if unlimited
+	maxPerDay      int
+	maxPerDayScope string
+	// the actions allowed; nil if any
+	actions []string
+}
+
+func (o *OptIn) settings(pod v1.Pod, namespace *v1.Namespace) (optInSettings, error) {
+	settings := optInSettings{maxPerDay: -1}
+
+	// the namespace first, so the pod overrides it
+	sources := []struct {
+		name        string
+		annotations map[string]string
+		scope       string
+	}{
+		{"namespace", nil, ScopeNamespace},
+		{"pod", pod.Annotations, ScopeOwner},
+	}
+	if namespace != nil {
+		sources[0].annotations = namespace.Annotations
+	}
+
+	for _, source := range sources {
+		if value, ok := source.annotations[AnnotationEnabled]; ok {
+			settings.enabled = value == "true"
+		}
+		if source.annotations[AnnotationDisabled] == "true" {
+			settings.disabled = true
+		}
+		if value, ok := source.annotations[AnnotationMaxPerDay]; ok {
+			maxPerDay, err := strconv.Atoi(value)
+			if err != nil || maxPerDay < 0 {
+				return settings, fmt.Errorf("invalid %s '%s' on %s", AnnotationMaxPerDay, value, source.name)
+			}
+			settings.maxPerDay = maxPerDay
+			settings.maxPerDayScope = source.scope
+		}
+		if value, ok := source.annotations[AnnotationActions]; ok {
+			settings.actions = []string{}
+			for _, action := range strings.Split(value, ",") {
+				settings.actions = append(settings.actions, strings.TrimSpace(action))
+			}
+		}
+	}
+	return settings, nil
+}
+
+// allows tells whether the settings let the action loose on the victim
+func (o *OptIn) allows(settings optInSettings, victim *Victim, blastRadius *BlastRadius, now time.Time) bool {
+	if settings.disabled || !settings.enabled {
+		return false
+	}
+
+	if settings.actions != nil {
+		allowed := false
+		for _, action := range settings.actions {
+			if action == o.Action {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	if settings.maxPerDay >= 0 {
+		limit := Limit{Scope: settings.maxPerDayScope, Max: settings.maxPerDay, Window: day}
+		if blastRadius.Count(limit, victim, now) >= settings.maxPerDay {
+			return false
+		}
+	}
+	return true
+}
+
+// filterPods removes the pods that didn't opt in. It counts the victims of the day with the blast
+// radius, and warns of pods whose annotations can't be made sense of.
+func (o *OptIn) filterPods(client kubernetes.Interface, pods []v1.Pod, cache *NamespaceCache, blastRadius *BlastRadius, now time.Time, logger log.FieldLogger) ([]v1.Pod, error) {
+	filteredList := []v1.Pod{}
+	for _, pod := range pods {
+		namespace, err := cache.Get(client, pod.Namespace, now)
+		if err != nil {
+			return nil, err
+		}
+
+		settings, err := o.settings(pod, namespace)
+		if err != nil {
+			// a mistyped annotation shouldn't let chaos loose
+			logger.WithFields(log.Fields{
+				"namespace": pod.Namespace,
+				"name":      pod.Name,
+				"err":       err,
+			}).Warn("ignoring pod with invalid opt-in annotations")
+			continue
+		}
+		if o.allows(settings, podVictim(pod, o.Action), blastRadius, now) {
+			filteredList = append(filteredList, pod)
+		}
+	}
+	return filteredList, nil
+}
@@ -0,0 +1,136 @@
+package chaoskube
+
+import (
+	"context"
+	"time"
+
+	"github.com/neo-technology/marmoset/chaoskube/action"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func annotatedNamespace(name string, annotations map[string]string) *v1.Namespace {
+	return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations}}
+}
+
+func annotatedPod(namespace, name string, annotations map[string]string) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, UID: types.UID("uid-" + name), Annotations: annotations},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+func (suite *Suite) TestOptIn() {
+	friday := ThankGodItsFriday{}.Now()
+	enabled := map[string]string{AnnotationEnabled: "true"}
+
+	for _, tt := range []struct {
+		name      string
+		namespace *v1.Namespace
+		pod       v1.Pod
+		expected  bool
+	}{
+		{"nobody opted in", annotatedNamespace("shop", nil), annotatedPod("shop", "foo", nil), false},
+		{"namespace opted in", annotatedNamespace("shop", enabled), annotatedPod("shop", "foo", nil), true},
+		{"pod opted in", annotatedNamespace("shop", nil), annotatedPod("shop", "foo", enabled), true},
+		{"namespace missing", nil, annotatedPod("shop", "foo", enabled), true},
+		{"pod opted out of namespace", annotatedNamespace("shop", enabled),
+			annotatedPod("shop", "foo", map[string]string{AnnotationEnabled: "false"}), false},
+		{"namespace disabled", annotatedNamespace("shop", map[string]string{AnnotationDisabled: "true"}),
+			annotatedPod("shop", "foo", enabled), false},
+		{"pod disabled", annotatedNamespace("shop", enabled),
+			annotatedPod("shop", "foo", map[string]string{AnnotationDisabled: "true"}), false},
+		{"action allowed", annotatedNamespace("shop", enabled),
+			annotatedPod("shop", "foo", map[string]string{AnnotationActions: "exec-pod, delete-pod"}), true},
+		{"action not allowed", annotatedNamespace("shop", map[string]string{AnnotationEnabled: "true", AnnotationActions: "exec-pod"}),
+			annotatedPod("shop", "foo", nil), false},
+		{"pod allows action the namespace doesn't", annotatedNamespace("shop", map[string]string{AnnotationEnabled: "true", AnnotationActions: "exec-pod"}),
+			annotatedPod("shop", "foo", map[string]string{AnnotationActions: "delete-pod"}), true},
+		{"none per day", annotatedNamespace("shop", map[string]string{AnnotationEnabled: "true", AnnotationMaxPerDay: "0"}),
+			annotatedPod("shop", "foo", nil), false},
+		{"invalid max per day", annotatedNamespace("shop", enabled),
+			annotatedPod("shop", "foo", map[string]string{AnnotationMaxPerDay: "lots"}), false},
+	} {
+		objects := []runtime.Object{}
+		if tt.namespace != nil {
+			objects = append(objects, tt.namespace)
+		}
+		optIn := &OptIn{Action: "delete-pod"}
+
+		pods, err := optIn.filterPods(fake.NewSimpleClientset(objects...), []v1.Pod{tt.pod}, &NamespaceCache{}, &BlastRadius{}, friday, logger)
+		suite.Require().NoError(err)
+		suite.Equal(tt.expected, len(pods) == 1, tt.name)
+	}
+}
+
+func (suite *Suite) TestOptInInvalidAnnotation() {
+	friday := ThankGodItsFriday{}.Now()
+	client := fake.NewSimpleClientset(annotatedNamespace("shop", map[string]string{AnnotationEnabled: "true"}))
+	pod := annotatedPod("shop", "foo", map[string]string{AnnotationMaxPerDay: "lots"})
+
+	pods, err := (&OptIn{Action: "delete-pod"}).filterPods(client, []v1.Pod{pod}, &NamespaceCache{}, &BlastRadius{}, friday, logger)
+	suite.Require().NoError(err)
+	suite.Empty(pods)
+	suite.assertLog(log.WarnLevel, "ignoring pod with invalid opt-in annotations", log.Fields{"namespace": "shop", "name": "foo"})
+	suite.Contains(logOutput.LastEntry().Data["err"].(error).Error(), "invalid marmoset/max-per-day 'lots' on pod")
+}
+
+func (suite *Suite) TestOptInMaxPerDay() {
+	friday := ThankGodItsFriday{}.Now()
+	client := fake.NewSimpleClientset(
+		annotatedNamespace("shop", map[string]string{AnnotationEnabled: "true", AnnotationMaxPerDay: "1"}),
+		annotatedNamespace("bank", map[string]string{AnnotationEnabled: "true"}),
+	)
+	shop := annotatedPod("shop", "foo", nil)
+	bank := annotatedPod("bank", "bar", map[string]string{AnnotationMaxPerDay: "2"})
+	optIn := &OptIn{Action: "delete-pod"}
+	cache := &NamespaceCache{}
+	blastRadius := &BlastRadius{Retention: day}
+
+	pods, err := optIn.filterPods(client, []v1.Pod{shop, bank}, cache, blastRadius, friday, logger)
+	suite.Require().NoError(err)
+	suite.Len(pods, 2)
+
+	// the namespace allows one victim a day
+	blastRadius.Strike(podVictim(shop, "delete pod"), friday)
+	pods, err = optIn.filterPods(client, []v1.Pod{shop, bank}, cache, blastRadius, friday.Add(time.Hour), logger)
+	suite.Require().NoError(err)
+	suite.Equal([]v1.Pod{bank}, pods)
+
+	// the pod two
+	blastRadius.Strike(podVictim(bank, "delete pod"), friday)
+	pods, err = optIn.filterPods(client, []v1.Pod{bank}, cache, blastRadius, friday.Add(time.Hour), logger)
+	suite.Require().NoError(err)
+	suite.Len(pods, 1)
+	blastRadius.Strike(podVictim(bank, "delete pod"), friday)
+	pods, err = optIn.filterPods(client, []v1.Pod{bank}, cache, blastRadius, friday.Add(time.Hour), logger)
+	suite.Require().NoError(err)
+	suite.Empty(pods)
+
+	// and tomorrow is another day
+	pods, err = optIn.filterPods(client, []v1.Pod{shop, bank}, cache, blastRadius, friday.Add(day), logger)
+	suite.Require().NoError(err)
+	suite.Len(pods, 2)
+}
+
+// TestOptInRetention tests that the opt-in mode keeps the victims of at least a day
+func (suite *Suite) TestOptInRetention() {
+	client := fake.NewSimpleClientset()
+	for _, tt := range []struct {
+		blastRadius *BlastRadius
+		expected    time.Duration
+	}{
+		{nil, day},
+		{&BlastRadius{Retention: time.Hour}, day},
+		{&BlastRadius{Retention: 2 * day}, 2 * day},
+	} {
+		spec := &PodChaosSpec{Action: action.NewDryRunPodAction(), OptIn: &OptIn{Action: "delete-pod"}, BlastRadius: tt.blastRadius, Logger: logger}
+		suite.Require().NoError(spec.Init(context.Background(), client))
+		suite.Equal(tt.expected, spec.BlastRadius.Retention)
+	}
+}
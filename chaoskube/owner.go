@@ -0,0 +1,265 @@
+package chaoskube
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neo-technology/marmoset/chaoskube/action"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// how many owners up the chain are followed, in case of a cycle
+const maxOwnerDepth = 5
+
+// OwnerFilter restricts chaos by the owners of pods, all the way up the chain of controllers: a
+// pod of a ReplicaSet of a Deployment is owned by both, and so is a pod of a Job of a CronJob.
+type OwnerFilter struct {
+	// kinds of owners a pod must have one of, e.g. StatefulSet; any if empty
+	Kinds []string `json:"kinds,omitempty"`
+	// kinds of owners a pod must have none of, e.g. Job and CronJob
+	ExcludedKinds []string `json:"excludedKinds,omitempty"`
+	// owners, as Kind/name in the namespace of the pod, a pod must have one of; any if empty
+	Owners []string `json:"owners,omitempty"`
+	// whether to exclude pods without a controller
+	ExcludeBare bool `json:"excludeBare,omitempty"`
+}
+
+// ParseOwnerFilter parses comma separated lists of kinds to include and exclude, and owners as
+// Kind/name
+func ParseOwnerFilter(kinds, excludedKinds string, owners []string, excludeBare bool) (*OwnerFilter, error) {
+	filter := &OwnerFilter{
+		Kinds:         splitList(kinds),
+		ExcludedKinds: splitList(excludedKinds),
+		Owners:        []string{},
+		ExcludeBare:   excludeBare,
+	}
+	for _, owner := range owners {
+		parts := strings.SplitN(strings.TrimSpace(owner), "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("Invalid owner '%v': must be <kind>/<name>", owner)
+		}
+		filter.Owners = append(filter.Owners, parts[0]+"/"+parts[1])
+	}
+	return filter, nil
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Empty tells whether the filter lets every pod through
+func (f *OwnerFilter) Empty() bool {
+	return len(f.Kinds) == 0 && len(f.ExcludedKinds) == 0 && len(f.Owners) == 0 && !f.ExcludeBare
+}
+
+func (f *OwnerFilter) String() string {
+	conditions := []string{}
+	if len(f.Kinds) > 0 {
+		conditions = append(conditions, "owned by a "+strings.Join(f.Kinds, " or "))
+	}
+	if len(f.ExcludedKinds) > 0 {
+		conditions = append(conditions, "not owned by a "+strings.Join(f.ExcludedKinds, " or "))
+	}
+	if len(f.Owners) > 0 {
+		conditions = append(conditions, "owned by "+strings.Join(f.Owners, " or "))
+	}
+	if f.ExcludeBare {
+		conditions = append(conditions, "has a controller")
+	}
+	return strings.Join(conditions, ", ")
+}
+
+// matches tells whether a pod with the given chain of owners passes the filter
+func (f *OwnerFilter) matches(chain []metav1.OwnerReference) bool {
+	if f.ExcludeBare && action.ControllerOf(chain) == nil {
+		return false
+	}
+	for _, kind := range f.ExcludedKinds {
+		if action.HasOwnerOfKind(chain, kind) {
+			return false
+		}
+	}
+	if len(f.Kinds) > 0 {
+		matched := false
+		for _, kind := range f.Kinds {
+			if action.HasOwnerOfKind(chain, kind) {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(f.Owners) > 0 {
+		matched := false
+		for _, owner := range chain {
+			for _, wanted := range f.Owners {
+				if owner.Kind+"/"+owner.Name == wanted {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// filterPods removes the pods whose owners don't pass the filter
+func (f *OwnerFilter) filterPods(client kubernetes.Interface, pods []v1.Pod, cache *OwnerCache, now time.Time) ([]v1.Pod, error) {
+	// empty filter returns original list
+	if f.Empty() {
+		return pods, nil
+	}
+
+	filteredList := []v1.Pod{}
+	for _, pod := range pods {
+		chain, err := cache.Chain(client, pod, now)
+		if err != nil {
+			return nil, err
+		}
+		if f.matches(chain) {
+			filteredList = append(filteredList, pod)
+		}
+	}
+	return filteredList, nil
+}
+
+// topOwned is a candidate presented as controlled by the controller at the top of its chain of
+// owners, so its owner is the Deployment or CronJob rather than one of its ReplicaSets or Jobs
+type topOwned struct {
+	metav1.Object
+	controller *metav1.OwnerReference
+}
+
+func (o *topOwned) GetOwnerReferences() []metav1.OwnerReference {
+	if o.controller == nil {
+		return nil
+	}
+	return []metav1.OwnerReference{*o.controller}
+}
+
+// topController returns the last controller in a chain of owners, or nil if there is none
+func topController(chain []metav1.OwnerReference) *metav1.OwnerReference {
+	var controller *metav1.OwnerReference
+	for i := range chain {
+		if chain[i].Controller != nil && *chain[i].Controller {
+			controller = &chain[i]
+		}
+	}
+	return controller
+}
+
+// ownedObjects returns the pods as objects to select from, owned by their top controllers
+func ownedObjects(client kubernetes.Interface, pods []v1.Pod, cache *OwnerCache, now time.Time) ([]metav1.Object, error) {
+	objects := make([]metav1.Object, 0, len(pods))
+	for i := range pods {
+		chain, err := cache.Chain(client, pods[i], now)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, &topOwned{Object: &pods[i], controller: topController(chain)})
+	}
+	return objects, nil
+}
+
+// OwnerCache resolves the owners of pods up the chain of controllers, and keeps the owners of
+// ReplicaSets and Jobs for a while, so filtering pods by their owners doesn't cost a request per pod
+type OwnerCache struct {
+	// how long the owners of an object are kept; zero keeps them forever
+	TTL time.Duration
+
+	// guards owners
+	mutex  sync.Mutex
+	owners map[string]cachedOwners
+}
+
+type cachedOwners struct {
+	// nil if the object is gone
+	owners  []metav1.OwnerReference
+	fetched time.Time
+}
+
+// NewOwnerCache returns a cache keeping owners for the given time
+func NewOwnerCache(ttl time.Duration) *OwnerCache {
+	return &OwnerCache{TTL: ttl}
+}
+
+// Chain returns the owners of a pod, followed by the owners of its controller and so on. Only
+// ReplicaSets and Jobs are looked up, as they are the controllers commonly owned in turn.
+func (c *OwnerCache) Chain(client kubernetes.Interface, pod v1.Pod, now time.Time) ([]metav1.OwnerReference, error) {
+	chain := []metav1.OwnerReference{}
+	owners := pod.OwnerReferences
+	for depth := 0; len(owners) > 0 && depth < maxOwnerDepth; depth++ {
+		chain = append(chain, owners...)
+
+		controller := action.ControllerOf(owners)
+		if controller == nil {
+			break
+		}
+		var err error
+		if owners, err = c.ownersOf(client, pod.Namespace, *controller, now); err != nil {
+			return nil, err
+		}
+	}
+	return chain, nil
+}
+
+// ownersOf returns the owners of the object the reference refers to, or nil if they aren't
+// looked up or the object is gone
+func (c *OwnerCache) ownersOf(client kubernetes.Interface, namespace string, reference metav1.OwnerReference, now time.Time) ([]metav1.OwnerReference, error) {
+	if reference.Kind != action.KindReplicaSet && reference.Kind != action.KindJob {
+		return nil, nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := namespace + "/" + reference.Kind + "/" + reference.Name
+	if cached, ok := c.owners[key]; ok && (c.TTL == 0 || now.Sub(cached.fetched) < c.TTL) {
+		return cached.owners, nil
+	}
+
+	var owners []metav1.OwnerReference
+	var err error
+	switch reference.Kind {
+	case action.KindReplicaSet:
+		replicaSet, getErr := client.AppsV1().ReplicaSets(namespace).Get(reference.Name, metav1.GetOptions{})
+		if getErr == nil {
+			owners = replicaSet.OwnerReferences
+		}
+		err = getErr
+	case action.KindJob:
+		job, getErr := client.BatchV1().Jobs(namespace).Get(reference.Name, metav1.GetOptions{})
+		if getErr == nil {
+			owners = job.OwnerReferences
+		}
+		err = getErr
+	}
+	if errors.IsNotFound(err) {
+		owners, err = nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s %s/%s: %s", reference.Kind, namespace, reference.Name, err)
+	}
+
+	if c.owners == nil {
+		c.owners = map[string]cachedOwners{}
+	}
+	c.owners[key] = cachedOwners{owners: owners, fetched: now}
+	return owners, nil
+}
@@ -0,0 +1,164 @@
+package chaoskube
+
+import (
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func controlledBy(kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+}
+
+func replicaSet(name, deployment string) *appsv1.ReplicaSet {
+	return &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Namespace:       "default",
+		Name:            name,
+		OwnerReferences: controlledBy("Deployment", deployment),
+	}}
+}
+
+func job(name, cronJob string) *batchv1.Job {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+	if cronJob != "" {
+		job.OwnerReferences = controlledBy("CronJob", cronJob)
+	}
+	return job
+}
+
+func ownerPod(name, kind, owner string) v1.Pod {
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+	if kind != "" {
+		pod.OwnerReferences = controlledBy(kind, owner)
+	}
+	return pod
+}
+
+func (suite *Suite) TestParseOwnerFilter() {
+	filter, err := ParseOwnerFilter("StatefulSet, Deployment", "Job,CronJob,", []string{"Deployment/frontend"}, true)
+	suite.Require().NoError(err)
+	suite.Equal(&OwnerFilter{
+		Kinds:         []string{"StatefulSet", "Deployment"},
+		ExcludedKinds: []string{"Job", "CronJob"},
+		Owners:        []string{"Deployment/frontend"},
+		ExcludeBare:   true,
+	}, filter)
+	suite.False(filter.Empty())
+
+	filter, err = ParseOwnerFilter("", "", nil, false)
+	suite.Require().NoError(err)
+	suite.True(filter.Empty())
+
+	for _, invalid := range []string{"frontend", "Deployment/", "/frontend"} {
+		_, err := ParseOwnerFilter("", "", []string{invalid}, false)
+		suite.Error(err, invalid)
+	}
+}
+
+func (suite *Suite) TestOwnerCacheChain() {
+	friday := ThankGodItsFriday{}.Now()
+	client := fake.NewSimpleClientset(replicaSet("frontend-123", "frontend"), job("backup-456", "backup"))
+	cache := NewOwnerCache(time.Minute)
+
+	for _, tt := range []struct {
+		pod      v1.Pod
+		expected []string
+	}{
+		{ownerPod("foo", "ReplicaSet", "frontend-123"), []string{"ReplicaSet/frontend-123", "Deployment/frontend"}},
+		{ownerPod("bar", "Job", "backup-456"), []string{"Job/backup-456", "CronJob/backup"}},
+		{ownerPod("baz", "StatefulSet", "db"), []string{"StatefulSet/db"}},
+		{ownerPod("qux", "ReplicaSet", "gone"), []string{"ReplicaSet/gone"}},
+		{ownerPod("quux", "", ""), []string{}},
+	} {
+		chain, err := cache.Chain(client, tt.pod, friday)
+		suite.Require().NoError(err)
+
+		owners := []string{}
+		for _, owner := range chain {
+			owners = append(owners, owner.Kind+"/"+owner.Name)
+		}
+		suite.Equal(tt.expected, owners, tt.pod.Name)
+	}
+	suite.Len(client.Actions(), 3)
+
+	// ReplicaSets and Jobs are cached, found or not
+	cache.Chain(client, ownerPod("foo", "ReplicaSet", "frontend-123"), friday.Add(30*time.Second))
+	cache.Chain(client, ownerPod("qux", "ReplicaSet", "gone"), friday.Add(30*time.Second))
+	suite.Len(client.Actions(), 3)
+
+	// until they expire
+	cache.Chain(client, ownerPod("foo", "ReplicaSet", "frontend-123"), friday.Add(time.Minute))
+	suite.Len(client.Actions(), 4)
+}
+
+func (suite *Suite) TestOwnerFilter() {
+	friday := ThankGodItsFriday{}.Now()
+	client := fake.NewSimpleClientset(
+		replicaSet("frontend-123", "frontend"),
+		replicaSet("backend-123", "backend"),
+		job("backup-456", "backup"),
+		job("migrate-789", ""),
+	)
+	pods := []v1.Pod{
+		ownerPod("frontend", "ReplicaSet", "frontend-123"),
+		ownerPod("backend", "ReplicaSet", "backend-123"),
+		ownerPod("backup", "Job", "backup-456"),
+		ownerPod("migrate", "Job", "migrate-789"),
+		ownerPod("db", "StatefulSet", "db"),
+		ownerPod("bare", "", ""),
+	}
+
+	for _, tt := range []struct {
+		filter   OwnerFilter
+		expected []string
+	}{
+		{OwnerFilter{}, []string{"frontend", "backend", "backup", "migrate", "db", "bare"}},
+		{OwnerFilter{Kinds: []string{"StatefulSet"}}, []string{"db"}},
+		{OwnerFilter{Kinds: []string{"Deployment", "StatefulSet"}}, []string{"frontend", "backend", "db"}},
+		{OwnerFilter{ExcludedKinds: []string{"Job", "CronJob"}}, []string{"frontend", "backend", "db", "bare"}},
+		{OwnerFilter{ExcludedKinds: []string{"CronJob"}}, []string{"frontend", "backend", "migrate", "db", "bare"}},
+		{OwnerFilter{Owners: []string{"Deployment/frontend"}}, []string{"frontend"}},
+		{OwnerFilter{Owners: []string{"ReplicaSet/backend-123", "StatefulSet/db"}}, []string{"backend", "db"}},
+		{OwnerFilter{ExcludeBare: true}, []string{"frontend", "backend", "backup", "migrate", "db"}},
+	} {
+		filtered, err := tt.filter.filterPods(client, pods, &OwnerCache{}, friday)
+		suite.Require().NoError(err)
+
+		names := []string{}
+		for _, pod := range filtered {
+			names = append(names, pod.Name)
+		}
+		suite.Equal(tt.expected, names, tt.filter.String())
+	}
+}
+
+func (suite *Suite) TestPodChaosSpecOwners() {
+	friday := ThankGodItsFriday{}.Now()
+	frontend := ownerPod("frontend", "ReplicaSet", "frontend-123")
+	backup := ownerPod("backup", "Job", "backup-456")
+	client := fake.NewSimpleClientset(replicaSet("frontend-123", "frontend"), job("backup-456", "backup"), &frontend, &backup)
+
+	spec := &PodChaosSpec{
+		Owners:      &OwnerFilter{ExcludedKinds: []string{"Job", "CronJob"}},
+		Labels:      labels.Everything(),
+		Annotations: labels.Everything(),
+		Namespaces:  labels.Everything(),
+		Logger:      logger,
+	}
+
+	report, err := spec.Candidates(client, friday)
+	suite.Require().NoError(err)
+	suite.Require().Len(report.Candidates, 1)
+	suite.Equal("frontend", report.Candidates[0].Name)
+	suite.Contains(report.Candidates[0].Reason, "not owned by a Job or CronJob")
+	suite.Contains(report.Filters, FilterCount{Filter: FilterOwner, Removed: 1})
+}
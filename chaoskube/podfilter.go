@@ -0,0 +1,239 @@
+package chaoskube
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/api/core/v1"
+)
+
+// PodFilter restricts chaos by the state and make-up of pods: killing a pod that is already failing
+// teaches nothing, and killing a system-critical one is dangerous
+type PodFilter struct {
+	// whether pods must be Ready
+	Ready bool `json:"ready,omitempty"`
+	// the most restarts any container of a pod may have had; negative if any number
+	MaxRestarts int `json:"maxRestarts"`
+	// QoS classes a pod must be of one of, e.g. BestEffort; any if empty
+	QOSClasses []v1.PodQOSClass `json:"qosClasses,omitempty"`
+	// PriorityClass names a pod must have one of; any if empty
+	PriorityClasses []string `json:"priorityClasses,omitempty"`
+	// PriorityClass names a pod must have none of, e.g. system-cluster-critical
+	ExcludedPriorityClasses []string `json:"excludedPriorityClasses,omitempty"`
+	// the highest priority value a pod may have; optional
+	MaxPriority *int32 `json:"maxPriority,omitempty"`
+	// patterns of images, with * matching anything, a container of a pod must run one of; any if
+	// empty
+	Images []string `json:"images,omitempty"`
+	// patterns of images no container of a pod may run
+	ExcludedImages []string `json:"excludedImages,omitempty"`
+}
+
+// ParsePodFilter parses comma separated lists of QoS classes, PriorityClass names and image
+// patterns, and a maximum priority value, which may be empty
+func ParsePodFilter(ready bool, maxRestarts int, qosClasses, priorityClasses, excludedPriorityClasses, maxPriority, images, excludedImages string) (*PodFilter, error) {
+	filter := &PodFilter{
+		Ready:                   ready,
+		MaxRestarts:             maxRestarts,
+		QOSClasses:              []v1.PodQOSClass{},
+		PriorityClasses:         splitList(priorityClasses),
+		ExcludedPriorityClasses: splitList(excludedPriorityClasses),
+		Images:                  splitList(images),
+		ExcludedImages:          splitList(excludedImages),
+	}
+
+	for _, class := range splitList(qosClasses) {
+		switch qosClass := v1.PodQOSClass(class); qosClass {
+		case v1.PodQOSGuaranteed, v1.PodQOSBurstable, v1.PodQOSBestEffort:
+			filter.QOSClasses = append(filter.QOSClasses, qosClass)
+		default:
+			return nil, fmt.Errorf("Invalid QoS class '%v': must be Guaranteed, Burstable or BestEffort", class)
+		}
+	}
+
+	if maxPriority = strings.TrimSpace(maxPriority); maxPriority != "" {
+		value, err := strconv.ParseInt(maxPriority, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid maximum priority '%v': must be a number", maxPriority)
+		}
+		priority := int32(value)
+		filter.MaxPriority = &priority
+	}
+	return filter, nil
+}
+
+// filterPods runs the pods through each configured filter, reporting what each removed
+func (f *PodFilter) filterPods(pods []v1.Pod, report *CandidateReport) []v1.Pod {
+	if f.Ready {
+		pods = report.removed(FilterReady, pods, filterByReady(pods))
+	}
+	if f.MaxRestarts >= 0 {
+		pods = report.removed(FilterRestarts, pods, filterByMaxRestarts(pods, f.MaxRestarts))
+	}
+	if len(f.QOSClasses) > 0 {
+		pods = report.removed(FilterQOSClass, pods, filterByQOSClasses(pods, f.QOSClasses))
+	}
+	if len(f.PriorityClasses) > 0 || len(f.ExcludedPriorityClasses) > 0 || f.MaxPriority != nil {
+		pods = report.removed(FilterPriority, pods, filterByPriority(pods, f.PriorityClasses, f.ExcludedPriorityClasses, f.MaxPriority))
+	}
+	if len(f.Images) > 0 || len(f.ExcludedImages) > 0 {
+		pods = report.removed(FilterImage, pods, filterByImages(pods, f.Images, f.ExcludedImages))
+	}
+	return pods
+}
+
+// reasons tells why a pod passed the filters
+func (f *PodFilter) reasons(pod v1.Pod) []string {
+	reasons := []string{}
+	if f.Ready {
+		reasons = append(reasons, "ready")
+	}
+	if f.MaxRestarts >= 0 {
+		reasons = append(reasons, fmt.Sprintf("%d restarts are at most %d", maxRestarts(pod), f.MaxRestarts))
+	}
+	if len(f.QOSClasses) > 0 {
+		reasons = append(reasons, fmt.Sprintf("QoS class is %s", pod.Status.QOSClass))
+	}
+	if len(f.PriorityClasses) > 0 || len(f.ExcludedPriorityClasses) > 0 || f.MaxPriority != nil {
+		reasons = append(reasons, fmt.Sprintf("priority %d of class '%s' allowed", priorityOf(pod), pod.Spec.PriorityClassName))
+	}
+	if len(f.Images) > 0 || len(f.ExcludedImages) > 0 {
+		reasons = append(reasons, "images allowed")
+	}
+	return reasons
+}
+
+// filterByReady filters a list of pods by whether they are Ready.
+func filterByReady(pods []v1.Pod) []v1.Pod {
+	filteredList := []v1.Pod{}
+
+	for _, pod := range pods {
+		if isReady(pod) {
+			filteredList = append(filteredList, pod)
+		}
+	}
+
+	return filteredList
+}
+
+// filterByMaxRestarts filters out pods with a container that restarted more than the given number
+// of times.
+func filterByMaxRestarts(pods []v1.Pod, max int) []v1.Pod {
+	filteredList := []v1.Pod{}
+
+	for _, pod := range pods {
+		if maxRestarts(pod) <= max {
+			filteredList = append(filteredList, pod)
+		}
+	}
+
+	return filteredList
+}
+
+// maxRestarts returns the restart count of the container of a pod that restarted most often
+func maxRestarts(pod v1.Pod) int {
+	restarts := 0
+	for _, status := range pod.Status.ContainerStatuses {
+		if int(status.RestartCount) > restarts {
+			restarts = int(status.RestartCount)
+		}
+	}
+	return restarts
+}
+
+// filterByQOSClasses filters a list of pods by their QoS class.
+func filterByQOSClasses(pods []v1.Pod, classes []v1.PodQOSClass) []v1.Pod {
+	filteredList := []v1.Pod{}
+
+	for _, pod := range pods {
+		for _, class := range classes {
+			if pod.Status.QOSClass == class {
+				filteredList = append(filteredList, pod)
+				break
+			}
+		}
+	}
+
+	return filteredList
+}
+
+// filterByPriority filters a list of pods by the name of their PriorityClass and their priority
+// value. Pods without a priority have priority 0.
+func filterByPriority(pods []v1.Pod, classes, excludedClasses []string, max *int32) []v1.Pod {
+	filteredList := []v1.Pod{}
+
+	for _, pod := range pods {
+		if len(classes) > 0 && !contains(classes, pod.Spec.PriorityClassName) {
+			continue
+		}
+		if contains(excludedClasses, pod.Spec.PriorityClassName) {
+			continue
+		}
+		if max != nil && priorityOf(pod) > *max {
+			continue
+		}
+		filteredList = append(filteredList, pod)
+	}
+
+	return filteredList
+}
+
+func priorityOf(pod v1.Pod) int32 {
+	if pod.Spec.Priority == nil {
+		return 0
+	}
+	return *pod.Spec.Priority
+}
+
+func contains(list []string, item string) bool {
+	for _, candidate := range list {
+		if candidate == item {
+			return true
+		}
+	}
+	return false
+}
+
+// filterByImages filters a list of pods by the images of their containers: one must match an
+// included pattern, if there are any, and none an excluded one.
+func filterByImages(pods []v1.Pod, images, excludedImages []string) []v1.Pod {
+	included := compileImagePatterns(images)
+	excluded := compileImagePatterns(excludedImages)
+
+	filteredList := []v1.Pod{}
+
+	for _, pod := range pods {
+		matched := len(included) == 0
+		excludedMatched := false
+		for _, container := range pod.Spec.Containers {
+			for _, pattern := range included {
+				if pattern.MatchString(container.Image) {
+					matched = true
+				}
+			}
+			for _, pattern := range excluded {
+				if pattern.MatchString(container.Image) {
+					excludedMatched = true
+				}
+			}
+		}
+		if matched && !excludedMatched {
+			filteredList = append(filteredList, pod)
+		}
+	}
+
+	return filteredList
+}
+
+// compileImagePatterns turns image patterns, in which * matches anything including slashes, into
+// regular expressions matching whole image names
+func compileImagePatterns(patterns []string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		expression := strings.Replace(regexp.QuoteMeta(pattern), `\*`, `.*`, -1)
+		compiled = append(compiled, regexp.MustCompile("^"+expression+"$"))
+	}
+	return compiled
+}
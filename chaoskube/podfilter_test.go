@@ -0,0 +1,138 @@
+package chaoskube
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// statusPod returns a running pod, changed by the given modifiers
+func statusPod(name string, modifiers ...func(*v1.Pod)) v1.Pod {
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "main", Image: "docker.io/library/nginx:1.15"}}},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+	for _, modify := range modifiers {
+		modify(&pod)
+	}
+	return pod
+}
+
+func ready(pod *v1.Pod) {
+	pod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+}
+
+func restarted(count int32) func(*v1.Pod) {
+	return func(pod *v1.Pod) {
+		pod.Status.ContainerStatuses = []v1.ContainerStatus{{Name: "main", RestartCount: count}}
+	}
+}
+
+func qosClass(class v1.PodQOSClass) func(*v1.Pod) {
+	return func(pod *v1.Pod) {
+		pod.Status.QOSClass = class
+	}
+}
+
+func priority(class string, value int32) func(*v1.Pod) {
+	return func(pod *v1.Pod) {
+		pod.Spec.PriorityClassName = class
+		pod.Spec.Priority = &value
+	}
+}
+
+func image(image string) func(*v1.Pod) {
+	return func(pod *v1.Pod) {
+		pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{Name: "sidecar", Image: image})
+	}
+}
+
+func podNames(pods []v1.Pod) []string {
+	names := []string{}
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	return names
+}
+
+func (suite *Suite) TestParsePodFilter() {
+	filter, err := ParsePodFilter(true, 3, "BestEffort, Burstable", "low", "system-cluster-critical,system-node-critical", "1000", "*/neo4j:*", "*:latest")
+	suite.Require().NoError(err)
+	maxPriority := int32(1000)
+	suite.Equal(&PodFilter{
+		Ready:                   true,
+		MaxRestarts:             3,
+		QOSClasses:              []v1.PodQOSClass{v1.PodQOSBestEffort, v1.PodQOSBurstable},
+		PriorityClasses:         []string{"low"},
+		ExcludedPriorityClasses: []string{"system-cluster-critical", "system-node-critical"},
+		MaxPriority:             &maxPriority,
+		Images:                  []string{"*/neo4j:*"},
+		ExcludedImages:          []string{"*:latest"},
+	}, filter)
+
+	filter, err = ParsePodFilter(false, -1, "", "", "", "", "", "")
+	suite.Require().NoError(err)
+	suite.Nil(filter.MaxPriority)
+
+	_, err = ParsePodFilter(false, -1, "Premium", "", "", "", "", "")
+	suite.Error(err)
+	_, err = ParsePodFilter(false, -1, "", "", "", "high", "", "")
+	suite.Error(err)
+}
+
+func (suite *Suite) TestPodFilter() {
+	maxPriority := int32(1000)
+	pods := []v1.Pod{
+		statusPod("ready", ready, restarted(0), qosClass(v1.PodQOSBestEffort)),
+		statusPod("unready", restarted(1), qosClass(v1.PodQOSBurstable)),
+		statusPod("crashing", ready, restarted(12), qosClass(v1.PodQOSGuaranteed)),
+		statusPod("critical", ready, priority("system-cluster-critical", 2000000000)),
+		statusPod("important", ready, priority("high", 5000)),
+		statusPod("neo4j", ready, image("neo4j/neo4j:3.4")),
+		statusPod("latest", ready, image("busybox:latest")),
+	}
+
+	for _, tt := range []struct {
+		filter   PodFilter
+		expected []string
+	}{
+		{PodFilter{MaxRestarts: -1}, []string{"ready", "unready", "crashing", "critical", "important", "neo4j", "latest"}},
+		{PodFilter{MaxRestarts: -1, Ready: true}, []string{"ready", "crashing", "critical", "important", "neo4j", "latest"}},
+		{PodFilter{MaxRestarts: 5}, []string{"ready", "unready", "critical", "important", "neo4j", "latest"}},
+		{PodFilter{MaxRestarts: 0}, []string{"ready", "critical", "important", "neo4j", "latest"}},
+		{PodFilter{MaxRestarts: -1, QOSClasses: []v1.PodQOSClass{v1.PodQOSBestEffort, v1.PodQOSBurstable}}, []string{"ready", "unready"}},
+		{PodFilter{MaxRestarts: -1, PriorityClasses: []string{"high"}}, []string{"important"}},
+		{PodFilter{MaxRestarts: -1, ExcludedPriorityClasses: []string{"system-cluster-critical"}}, []string{"ready", "unready", "crashing", "important", "neo4j", "latest"}},
+		{PodFilter{MaxRestarts: -1, MaxPriority: &maxPriority}, []string{"ready", "unready", "crashing", "neo4j", "latest"}},
+		{PodFilter{MaxRestarts: -1, Images: []string{"*neo4j:*"}}, []string{"neo4j"}},
+		{PodFilter{MaxRestarts: -1, Images: []string{"*nginx*"}, ExcludedImages: []string{"*:latest"}}, []string{"ready", "unready", "crashing", "critical", "important", "neo4j"}},
+	} {
+		report := newCandidateReport(len(pods))
+		suite.Equal(tt.expected, podNames(tt.filter.filterPods(pods, report)))
+	}
+}
+
+func (suite *Suite) TestPodChaosSpecPodFilter() {
+	friday := ThankGodItsFriday{}.Now()
+	healthy := statusPod("healthy", ready)
+	crashing := statusPod("crashing", ready, restarted(7))
+	client := fake.NewSimpleClientset(&healthy, &crashing)
+
+	spec := &PodChaosSpec{
+		Filter:      &PodFilter{Ready: true, MaxRestarts: 3},
+		Labels:      labels.Everything(),
+		Annotations: labels.Everything(),
+		Namespaces:  labels.Everything(),
+		Logger:      logger,
+	}
+
+	report, err := spec.Candidates(client, friday)
+	suite.Require().NoError(err)
+	suite.Require().Len(report.Candidates, 1)
+	suite.Equal("healthy", report.Candidates[0].Name)
+	suite.Contains(report.Candidates[0].Reason, "ready, 0 restarts are at most 3")
+	suite.Contains(report.Filters, FilterCount{Filter: FilterReady, Removed: 0})
+	suite.Contains(report.Filters, FilterCount{Filter: FilterRestarts, Removed: 1})
+}
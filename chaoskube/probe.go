@@ -0,0 +1,309 @@
+package chaoskube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// When probes run
+	probePhaseBefore = "before"
+	probePhaseAfter  = "after"
+
+	// how often the pods of a PodsReadyProbe are checked while waiting for them
+	podsReadyInterval = 1 * time.Second
+)
+
+// Probe checks one part of the steady-state hypothesis of an experiment: that everything is well
+// before chaos strikes, and again after
+type Probe interface {
+	// Check returns why the steady state doesn't hold, or nil if it does; giving up when the
+	// context is done
+	Check(ctx context.Context, client kubernetes.Interface) error
+	// Human-readable description of the probe
+	String() string
+}
+
+// HTTPProbe expects a GET of URL to answer with the expected status code and, if given, a body
+// containing the expected text
+type HTTPProbe struct {
+	URL            string
+	ExpectedStatus int
+	ExpectedBody   string
+	Timeout        time.Duration
+}
+
+func (p *HTTPProbe) Check(ctx context.Context, client kubernetes.Interface) error {
+	request, err := http.NewRequest(http.MethodGet, p.URL, nil)
+	if err != nil {
+		return err
+	}
+	httpClient := &http.Client{Timeout: p.Timeout}
+	response, err := httpClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != p.ExpectedStatus {
+		return fmt.Errorf("%s responded %s, expected %d", p.URL, response.Status, p.ExpectedStatus)
+	}
+	if p.ExpectedBody != "" {
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(body), p.ExpectedBody) {
+			return fmt.Errorf("%s responded without '%s'", p.URL, p.ExpectedBody)
+		}
+	}
+	return nil
+}
+
+func (p *HTTPProbe) String() string {
+	return "http " + p.URL
+}
+
+// PrometheusProbe runs an instant query against a Prometheus server. The steady state holds if
+// the result isn't empty and none of its values is zero, so both 'up{job="frontend"}' and
+// 'rate(errors_total[5m]) < 0.1' make sensible queries.
+type PrometheusProbe struct {
+	// the base URL of the Prometheus server, e.g. http://prometheus:9090
+	Endpoint string
+	Query    string
+	Timeout  time.Duration
+}
+
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+type prometheusSample struct {
+	Value []interface{} `json:"value"`
+}
+
+func (p *PrometheusProbe) Check(ctx context.Context, client kubernetes.Interface) error {
+	queryURL := strings.TrimSuffix(p.Endpoint, "/") + "/api/v1/query?query=" + url.QueryEscape(p.Query)
+	request, err := http.NewRequest(http.MethodGet, queryURL, nil)
+	if err != nil {
+		return err
+	}
+
+	httpClient := &http.Client{Timeout: p.Timeout}
+	httpResponse, err := httpClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+
+	response := prometheusResponse{}
+	if err := json.NewDecoder(httpResponse.Body).Decode(&response); err != nil {
+		return fmt.Errorf("invalid response from Prometheus: %s", err)
+	}
+	if response.Status != "success" {
+		return fmt.Errorf("query '%s' failed: %s", p.Query, response.Error)
+	}
+
+	values := [][]interface{}{}
+	switch response.Data.ResultType {
+	case "vector":
+		samples := []prometheusSample{}
+		if err := json.Unmarshal(response.Data.Result, &samples); err != nil {
+			return fmt.Errorf("invalid response from Prometheus: %s", err)
+		}
+		for _, sample := range samples {
+			values = append(values, sample.Value)
+		}
+	case "scalar":
+		value := []interface{}{}
+		if err := json.Unmarshal(response.Data.Result, &value); err != nil {
+			return fmt.Errorf("invalid response from Prometheus: %s", err)
+		}
+		values = append(values, value)
+	default:
+		return fmt.Errorf("query '%s' returned a %s, expected a vector or scalar", p.Query, response.Data.ResultType)
+	}
+
+	if len(values) == 0 {
+		return fmt.Errorf("query '%s' returned nothing", p.Query)
+	}
+	for _, value := range values {
+		// values come as [timestamp, "value"]
+		if len(value) != 2 {
+			return fmt.Errorf("invalid value from Prometheus: %v", value)
+		}
+		text, _ := value[1].(string)
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("invalid value from Prometheus: %v", value)
+		}
+		if number == 0 {
+			return fmt.Errorf("query '%s' returned 0", p.Query)
+		}
+	}
+	return nil
+}
+
+func (p *PrometheusProbe) String() string {
+	return "prometheus " + p.Query
+}
+
+// PodsReadyProbe expects all pods matching Selector in Namespace to be Ready within the given
+// time. If none match, the steady state doesn't hold.
+type PodsReadyProbe struct {
+	Namespace string
+	Selector  labels.Selector
+	Within    time.Duration
+}
+
+func (p *PodsReadyProbe) Check(ctx context.Context, client kubernetes.Interface) error {
+	deadline := time.Now().Add(p.Within)
+	for {
+		err := p.check(client)
+		if err == nil || !time.Now().Before(deadline) {
+			return err
+		}
+		select {
+		case <-time.After(podsReadyInterval):
+		case <-ctx.Done():
+			return fmt.Errorf("%s, gave up waiting: %s", err, ctx.Err())
+		}
+	}
+}
+
+func (p *PodsReadyProbe) check(client kubernetes.Interface) error {
+	podList, err := client.CoreV1().Pods(p.Namespace).List(metav1.ListOptions{LabelSelector: p.Selector.String()})
+	if err != nil {
+		return err
+	}
+	if len(podList.Items) == 0 {
+		return fmt.Errorf("no pods match '%s' in namespace %s", p.Selector, p.Namespace)
+	}
+
+	notReady := []string{}
+	for _, pod := range podList.Items {
+		if !isReady(pod) {
+			notReady = append(notReady, pod.Name)
+		}
+	}
+	if len(notReady) > 0 {
+		return fmt.Errorf("pods not ready: %s", strings.Join(notReady, ", "))
+	}
+	return nil
+}
+
+func (p *PodsReadyProbe) String() string {
+	return fmt.Sprintf("pods ready %s/%s within %s", p.Namespace, p.Selector, p.Within)
+}
+
+// isReady tells whether a pod has the Ready condition
+func isReady(pod v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// ParseProbe parses a probe, written as http=<url>[;status=<code>][;body=<text>],
+// prometheus=<query> or pods-ready=<namespace>/<selector>[;within=<duration>]. Prometheus queries
+// go to prometheusURL; HTTP requests give up after timeout.
+func ParseProbe(probe, prometheusURL string, timeout time.Duration) (Probe, error) {
+	kind, value := probe, ""
+	if i := strings.Index(probe, "="); i >= 0 {
+		kind, value = strings.TrimSpace(probe[:i]), strings.TrimSpace(probe[i+1:])
+	}
+	if value == "" {
+		return nil, fmt.Errorf("Invalid probe '%v': must be http=..., prometheus=... or pods-ready=...", probe)
+	}
+
+	switch kind {
+	case "http":
+		parts := strings.Split(value, ";")
+		httpProbe := &HTTPProbe{URL: parts[0], ExpectedStatus: http.StatusOK, Timeout: timeout}
+		for _, option := range parts[1:] {
+			key, optionValue := splitOption(option)
+			switch key {
+			case "status":
+				status, err := strconv.Atoi(optionValue)
+				if err != nil {
+					return nil, fmt.Errorf("Invalid probe '%v': invalid status '%v'", probe, optionValue)
+				}
+				httpProbe.ExpectedStatus = status
+			case "body":
+				httpProbe.ExpectedBody = optionValue
+			default:
+				return nil, fmt.Errorf("Invalid probe '%v': unknown option '%v'", probe, key)
+			}
+		}
+		return httpProbe, nil
+
+	case "prometheus":
+		if prometheusURL == "" {
+			return nil, fmt.Errorf("Invalid probe '%v': no Prometheus URL given", probe)
+		}
+		return &PrometheusProbe{Endpoint: prometheusURL, Query: value, Timeout: timeout}, nil
+
+	case "pods-ready":
+		parts := strings.Split(value, ";")
+		target := strings.SplitN(parts[0], "/", 2)
+		if len(target) != 2 || target[0] == "" {
+			return nil, fmt.Errorf("Invalid probe '%v': must be pods-ready=<namespace>/<selector>", probe)
+		}
+		selector, err := labels.Parse(target[1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid probe '%v': %s", probe, err)
+		}
+		podsProbe := &PodsReadyProbe{Namespace: target[0], Selector: selector}
+		for _, option := range parts[1:] {
+			key, optionValue := splitOption(option)
+			if key != "within" {
+				return nil, fmt.Errorf("Invalid probe '%v': unknown option '%v'", probe, key)
+			}
+			within, err := time.ParseDuration(optionValue)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid probe '%v': invalid duration '%v'", probe, optionValue)
+			}
+			podsProbe.Within = within
+		}
+		return podsProbe, nil
+	}
+
+	return nil, fmt.Errorf("Invalid probe '%v': unknown kind '%v'", probe, kind)
+}
+
+func splitOption(option string) (string, string) {
+	parts := strings.SplitN(option, "=", 2)
+	if len(parts) == 1 {
+		return strings.TrimSpace(parts[0]), ""
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+}
+
+// checkProbes runs all probes, and returns why the first failing one failed
+func checkProbes(ctx context.Context, client kubernetes.Interface, probes []Probe) error {
+	for _, probe := range probes {
+		if err := probe.Check(ctx, client); err != nil {
+			return fmt.Errorf("%s: %s", probe, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package chaoskube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/neo-technology/marmoset/util"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+// stubProbe fails with the errors given, one per check, and passes once they run out
+type stubProbe struct {
+	errs   []error
+	checks int
+}
+
+func (p *stubProbe) Check(ctx context.Context, client kubernetes.Interface) error {
+	p.checks++
+	if len(p.errs) == 0 {
+		return nil
+	}
+	err := p.errs[0]
+	p.errs = p.errs[1:]
+	return err
+}
+
+func (p *stubProbe) String() string {
+	return "stub"
+}
+
+// failingSpec fails its action on one victim
+type failingSpec struct{}
+
+func (s *failingSpec) Init(ctx context.Context, client kubernetes.Interface) error {
+	return nil
+}
+
+func (s *failingSpec) Apply(ctx context.Context, client kubernetes.Interface, now time.Time) ([]*Victim, error) {
+	victim := &Victim{Kind: KindPod, Namespace: "default", Name: "foo", Action: "delete pod", Error: "forbidden"}
+	return []*Victim{victim}, errors.New("forbidden")
+}
+
+func (s *failingSpec) Candidates(client kubernetes.Interface, now time.Time) (*CandidateReport, error) {
+	return newCandidateReport(0), nil
+}
+
+func (suite *Suite) TestHTTPProbe() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "all good")
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		probe *HTTPProbe
+		ok    bool
+	}{
+		{&HTTPProbe{URL: server.URL, ExpectedStatus: http.StatusOK}, true},
+		{&HTTPProbe{URL: server.URL, ExpectedStatus: http.StatusOK, ExpectedBody: "good"}, true},
+		{&HTTPProbe{URL: server.URL, ExpectedStatus: http.StatusOK, ExpectedBody: "bad"}, false},
+		{&HTTPProbe{URL: server.URL + "/broken", ExpectedStatus: http.StatusOK}, false},
+		{&HTTPProbe{URL: server.URL + "/broken", ExpectedStatus: http.StatusServiceUnavailable}, true},
+	} {
+		err := tt.probe.Check(context.Background(), nil)
+		suite.Equal(tt.ok, err == nil, "%+v: %v", tt.probe, err)
+	}
+}
+
+func (suite *Suite) TestPrometheusProbe() {
+	for _, tt := range []struct {
+		response string
+		ok       bool
+	}{
+		{`{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {}, "value": [1, "1"]}]}}`, true},
+		{`{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {}, "value": [1, "1"]}, {"metric": {}, "value": [1, "0"]}]}}`, false},
+		{`{"status": "success", "data": {"resultType": "vector", "result": []}}`, false},
+		{`{"status": "success", "data": {"resultType": "scalar", "result": [1, "0.5"]}}`, true},
+		{`{"status": "success", "data": {"resultType": "scalar", "result": [1, "0"]}}`, false},
+		{`{"status": "success", "data": {"resultType": "matrix", "result": []}}`, false},
+		{`{"status": "error", "error": "parse error"}`, false},
+		{`nonsense`, false},
+	} {
+		var query string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.Query().Get("query")
+			fmt.Fprint(w, tt.response)
+		}))
+
+		err := (&PrometheusProbe{Endpoint: server.URL + "/", Query: `up{job="frontend"}`}).Check(context.Background(), nil)
+		suite.Equal(tt.ok, err == nil, "%s: %v", tt.response, err)
+		suite.Equal(`up{job="frontend"}`, query)
+
+		server.Close()
+	}
+}
+
+func (suite *Suite) TestPodsReadyProbe() {
+	readyPod := func(name string, ready v1.ConditionStatus) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: name, Labels: map[string]string{"app": "frontend"}},
+			Status:     v1.PodStatus{Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: ready}}},
+		}
+	}
+	selector, err := labels.Parse("app=frontend")
+	suite.Require().NoError(err)
+	probe := &PodsReadyProbe{Namespace: "shop", Selector: selector}
+
+	suite.Error(probe.Check(context.Background(), fake.NewSimpleClientset()))
+	suite.NoError(probe.Check(context.Background(), fake.NewSimpleClientset(readyPod("foo", v1.ConditionTrue))))
+	suite.Error(probe.Check(context.Background(), fake.NewSimpleClientset(readyPod("foo", v1.ConditionTrue), readyPod("bar", v1.ConditionFalse))))
+
+	// waiting for pods to become Ready stops once the run is cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	probe.Within = time.Minute
+	start := time.Now()
+	err = probe.Check(ctx, fake.NewSimpleClientset(readyPod("bar", v1.ConditionFalse)))
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "gave up waiting")
+	suite.True(time.Since(start) < podsReadyInterval)
+}
+
+func (suite *Suite) TestParseProbe() {
+	probe, err := ParseProbe("http=http://frontend/health;status=204;body=ok", "", 5*time.Second)
+	suite.Require().NoError(err)
+	suite.Equal(&HTTPProbe{URL: "http://frontend/health", ExpectedStatus: 204, ExpectedBody: "ok", Timeout: 5 * time.Second}, probe)
+
+	probe, err = ParseProbe(`prometheus=up{job="frontend"} == 1`, "http://prometheus:9090", 5*time.Second)
+	suite.Require().NoError(err)
+	suite.Equal(&PrometheusProbe{Endpoint: "http://prometheus:9090", Query: `up{job="frontend"} == 1`, Timeout: 5 * time.Second}, probe)
+
+	probe, err = ParseProbe("pods-ready=shop/app=frontend,tier!=cache;within=2m", "", 5*time.Second)
+	suite.Require().NoError(err)
+	podsProbe, ok := probe.(*PodsReadyProbe)
+	suite.Require().True(ok)
+	suite.Equal("shop", podsProbe.Namespace)
+	suite.Equal("app=frontend,tier!=cache", podsProbe.Selector.String())
+	suite.Equal(2*time.Minute, podsProbe.Within)
+
+	for _, invalid := range []string{
+		"",
+		"http",
+		"http=http://frontend;status=ok",
+		"http=http://frontend;colour=red",
+		"prometheus=up",
+		"pods-ready=app=frontend",
+		"pods-ready=shop/app=frontend;within=soon",
+		"dns=frontend",
+	} {
+		_, err := ParseProbe(invalid, "", time.Second)
+		suite.Error(err, invalid)
+	}
+}
+
+// TestSteadyStateNotMet tests that no chaos is attempted while a probe fails
+func (suite *Suite) TestSteadyStateNotMet() {
+	chaoskube := suite.setupOnFriday()
+	recorder := &chaosRecorder{}
+	chaoskube.Spec = recorder
+	chaoskube.Probes = []Probe{&stubProbe{errs: []error{errors.New("frontend down")}}}
+
+	outcome, err := chaoskube.RunOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.False(recorder.invoked)
+	suite.Require().NotNil(outcome.Exclusion)
+	suite.Equal(msgSteadyStateNotMet, outcome.Exclusion.Reason)
+	suite.assertLog(log.InfoLevel, msgSteadyStateNotMet, log.Fields{"err": "stub: frontend down"})
+
+	outcome, err = chaoskube.RunOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.True(recorder.invoked)
+	suite.Nil(outcome.Exclusion)
+}
+
+// TestSteadyStateNotRestored tests that a probe failing after an action fails the run, tells the
+// owners of the victim and pauses chaos if configured to
+func (suite *Suite) TestSteadyStateNotRestored() {
+	chaoskube := suite.setupWithPods(
+		labels.Everything(),
+		labels.Everything(),
+		labels.Everything(),
+		[]time.Weekday{},
+		[]util.TimePeriod{},
+		[]time.Time{},
+		time.UTC,
+		time.Duration(0),
+		true,
+	)
+	chaoskube.Now = ThankGodItsFriday{}.Now
+	recorder := record.NewFakeRecorder(10)
+	chaoskube.Experiment = &Experiment{Name: "experiment", Instance: "marmoset-1", Recorder: recorder}
+	probe := &stubProbe{errs: []error{nil, errors.New("frontend down")}}
+	chaoskube.Probes = []Probe{probe}
+	chaoskube.PauseOnProbeFailure = true
+
+	outcome, err := chaoskube.RunOnce(context.Background())
+	suite.Require().Error(err)
+	suite.Equal(2, probe.checks)
+	suite.Require().Len(outcome.Victims, 1)
+	suite.Contains(outcome.Victims[0].Error, "frontend down")
+	suite.Contains(outcome.Error, "steady state not restored")
+
+	select {
+	case event := <-recorder.Events:
+		suite.Contains(event, ReasonSteadyStateViolated)
+		suite.Contains(event, "frontend down")
+	default:
+		suite.Fail("no event recorded")
+	}
+
+	// the pause shows in the gauge right away, not on the next run
+	suite.Equal(1.0, pausedValue())
+	exclusion, err := chaoskube.Exclusion(chaoskube.Now())
+	suite.Require().NoError(err)
+	suite.Require().NotNil(exclusion)
+	suite.Equal(msgPaused, exclusion.Reason)
+}
+
+// TestSteadyStateCheckedAfterFailedAction tests that the probes run after an action that failed on
+// its victims, and that both failures make it into the error of the run
+func (suite *Suite) TestSteadyStateCheckedAfterFailedAction() {
+	chaoskube := suite.setupOnFriday()
+	chaoskube.Spec = &failingSpec{}
+	probe := &stubProbe{errs: []error{nil, errors.New("frontend down")}}
+	chaoskube.Probes = []Probe{probe}
+
+	outcome, err := chaoskube.RunOnce(context.Background())
+	suite.Require().Error(err)
+	suite.Equal(2, probe.checks)
+	suite.Contains(err.Error(), "forbidden")
+	suite.Contains(err.Error(), "frontend down")
+	suite.Require().Len(outcome.Victims, 1)
+	suite.Equal("forbidden", outcome.Victims[0].Error)
+}
@@ -0,0 +1,167 @@
+package chaoskube
+
+import (
+	"context"
+	"time"
+
+	"github.com/neo-technology/marmoset/chaoskube/action"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Recovery measures how long the owners of victims take to have as many Ready pods as they want
+// again, turning each run into a measurement of resilience
+type Recovery struct {
+	// how long to wait for an owner to recover before giving up
+	Timeout time.Duration
+	// how long recovery may take before the run is flagged; no limit if zero
+	SLO time.Duration
+	// how often to check the owner; every second if zero
+	Interval time.Duration
+}
+
+// RecoveryResult tells how the owner of a victim recovered
+type RecoveryResult struct {
+	// whether the owner had as many Ready pods as it wants within the timeout
+	Recovered bool `json:"recovered"`
+	// how long that took, or how long was waited if it didn't recover
+	Seconds float64 `json:"seconds"`
+	// whether recovery took longer than the SLO allows, or didn't happen
+	SLOExceeded bool `json:"sloExceeded,omitempty"`
+}
+
+// measure waits until the owner of the pod recovers, and records how long that took. It returns
+// nil if the pod has no owner wanting a number of pods, or waiting was cancelled or failed.
+func (r *Recovery) measure(ctx context.Context, client kubernetes.Interface, pod v1.Pod, actionName string, logger log.FieldLogger) *RecoveryResult {
+	controller := action.ControllerOf(pod.OwnerReferences)
+	if controller == nil || !recoverable(controller.Kind) {
+		return nil
+	}
+	logger = logger.WithFields(log.Fields{
+		"namespace": pod.Namespace,
+		"name":      pod.Name,
+		"owner":     controller.Kind + "/" + controller.Name,
+	})
+
+	interval := r.Interval
+	if interval == 0 {
+		interval = time.Second
+	}
+	start := time.Now()
+	timeoutCtx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	err := wait.PollImmediateUntil(interval, func() (bool, error) {
+		return recovered(client, pod, controller)
+	}, timeoutCtx.Done())
+	elapsed := time.Since(start)
+
+	result := &RecoveryResult{Seconds: elapsed.Seconds()}
+	switch {
+	case err == wait.ErrWaitTimeout && ctx.Err() != nil:
+		logger.WithField("err", ctx.Err()).Debug("gave up measuring recovery")
+		return nil
+	case err == wait.ErrWaitTimeout:
+		result.SLOExceeded = true
+		logger.WithField("timeout", r.Timeout).Warn("owner did not recover")
+	case err != nil:
+		logger.WithField("err", err).Error("failed to measure recovery")
+		return nil
+	default:
+		result.Recovered = true
+		result.SLOExceeded = r.SLO > 0 && elapsed > r.SLO
+		recoverySeconds.WithLabelValues(actionName).Observe(result.Seconds)
+		logger.WithField("recovery", elapsed.Truncate(time.Millisecond).String()).Info("owner recovered")
+		if result.SLOExceeded {
+			logger.WithField("slo", r.SLO).Warn("recovery exceeded SLO")
+		}
+	}
+
+	if result.SLOExceeded {
+		recoverySLOViolations.WithLabelValues(actionName).Inc()
+	}
+	return result
+}
+
+// recoverable tells whether owners of the kind want a number of pods
+func recoverable(kind string) bool {
+	return kind == action.KindReplicaSet || kind == action.KindStatefulSet || kind == action.KindDaemonSet
+}
+
+// recovered tells whether the controller of the pod has as many Ready pods as it wants. Pods being
+// deleted, like a victim terminating gracefully, don't count.
+func recovered(client kubernetes.Interface, pod v1.Pod, controller *metav1.OwnerReference) (bool, error) {
+	desired, selector, err := desiredPods(client, pod.Namespace, controller)
+	if err != nil {
+		return false, err
+	}
+
+	podList, err := client.CoreV1().Pods(pod.Namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return false, err
+	}
+
+	ready := int32(0)
+	for _, candidate := range podList.Items {
+		owner := action.ControllerOf(candidate.OwnerReferences)
+		if owner == nil || owner.UID != controller.UID || candidate.DeletionTimestamp != nil {
+			continue
+		}
+		if isReady(candidate) {
+			ready++
+		}
+	}
+	return ready >= desired, nil
+}
+
+// desiredPods returns how many pods a ReplicaSet, Deployment, StatefulSet or DaemonSet wants, and
+// the selector of its pods
+func desiredPods(client kubernetes.Interface, namespace string, controller *metav1.OwnerReference) (int32, labels.Selector, error) {
+	var desired int32
+	var selector *metav1.LabelSelector
+
+	switch controller.Kind {
+	case action.KindReplicaSet:
+		replicaSet, err := client.AppsV1().ReplicaSets(namespace).Get(controller.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, nil, err
+		}
+		desired, selector = replicas(replicaSet.Spec.Replicas), replicaSet.Spec.Selector
+	case action.KindDeployment:
+		deployment, err := client.AppsV1().Deployments(namespace).Get(controller.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, nil, err
+		}
+		desired, selector = replicas(deployment.Spec.Replicas), deployment.Spec.Selector
+	case action.KindStatefulSet:
+		statefulSet, err := client.AppsV1().StatefulSets(namespace).Get(controller.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, nil, err
+		}
+		desired, selector = replicas(statefulSet.Spec.Replicas), statefulSet.Spec.Selector
+	case action.KindDaemonSet:
+		daemonSet, err := client.AppsV1().DaemonSets(namespace).Get(controller.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, nil, err
+		}
+		desired, selector = daemonSet.Status.DesiredNumberScheduled, daemonSet.Spec.Selector
+	}
+
+	podSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return 0, nil, err
+	}
+	return desired, podSelector, nil
+}
+
+// replicas returns the number of replicas asked for, which defaults to one
+func replicas(asked *int32) int32 {
+	if asked == nil {
+		return 1
+	}
+	return *asked
+}
@@ -0,0 +1,107 @@
+package chaoskube
+
+import (
+	"context"
+	"time"
+
+	"github.com/neo-technology/marmoset/chaoskube/action"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func webReplicaSet(replicas int32) *appsv1.ReplicaSet {
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-123", UID: types.UID("web-123")},
+		Spec: appsv1.ReplicaSetSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+	}
+}
+
+// webPod returns a pod of the web-123 ReplicaSet, changed by the given modifiers
+func webPod(name string, modifiers ...func(*v1.Pod)) *v1.Pod {
+	pod := statusPod(name, modifiers...)
+	pod.UID = types.UID(name)
+	pod.Labels = map[string]string{"app": "web"}
+	pod.OwnerReferences = controlledBy(action.KindReplicaSet, "web-123")
+	pod.OwnerReferences[0].UID = types.UID("web-123")
+	return &pod
+}
+
+func terminating(pod *v1.Pod) {
+	now := metav1.Now()
+	pod.DeletionTimestamp = &now
+}
+
+func (suite *Suite) TestRecoveryMeasure() {
+	for _, tt := range []struct {
+		recovery Recovery
+		pods     []*v1.Pod
+		expected *RecoveryResult
+	}{
+		// the victim is gone and its replacement Ready
+		{Recovery{Timeout: time.Minute, SLO: time.Minute}, []*v1.Pod{webPod("web-1", ready), webPod("web-3", ready)}, &RecoveryResult{Recovered: true}},
+		// the replacement isn't Ready yet
+		{Recovery{Timeout: 50 * time.Millisecond}, []*v1.Pod{webPod("web-1", ready), webPod("web-3")}, &RecoveryResult{SLOExceeded: true}},
+		// the victim is still terminating
+		{Recovery{Timeout: 50 * time.Millisecond}, []*v1.Pod{webPod("web-1", ready), webPod("web-2", ready, terminating)}, &RecoveryResult{SLOExceeded: true}},
+		// recovered, but slower than the SLO
+		{Recovery{Timeout: time.Minute, SLO: time.Nanosecond}, []*v1.Pod{webPod("web-1", ready), webPod("web-3", ready)}, &RecoveryResult{Recovered: true, SLOExceeded: true}},
+	} {
+		objects := []runtime.Object{webReplicaSet(2)}
+		for _, pod := range tt.pods {
+			objects = append(objects, pod)
+		}
+		client := fake.NewSimpleClientset(objects...)
+		tt.recovery.Interval = 10 * time.Millisecond
+
+		result := tt.recovery.measure(context.Background(), client, *webPod("web-2"), "delete pod", logger)
+		suite.Require().NotNil(result)
+		suite.Equal(tt.expected.Recovered, result.Recovered)
+		suite.Equal(tt.expected.SLOExceeded, result.SLOExceeded)
+	}
+}
+
+func (suite *Suite) TestRecoveryNotMeasured() {
+	client := fake.NewSimpleClientset(webReplicaSet(2))
+	recovery := &Recovery{Timeout: time.Minute}
+
+	// bare pods and pods of Jobs have no number of pods to get back to
+	suite.Nil(recovery.measure(context.Background(), client, statusPod("bare"), "delete pod", logger))
+	suite.Nil(recovery.measure(context.Background(), client, ownerPod("backup", action.KindJob, "backup-456"), "delete pod", logger))
+
+	// nor is anything measured once the run is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	suite.Nil(recovery.measure(ctx, client, *webPod("web-2"), "delete pod", logger))
+}
+
+func (suite *Suite) TestPodChaosSpecRecovery() {
+	friday := ThankGodItsFriday{}.Now()
+	client := fake.NewSimpleClientset(webReplicaSet(1), webPod("web-1", ready))
+
+	spec := &PodChaosSpec{
+		Action:      action.NewDeletePodAction(client),
+		Recovery:    &Recovery{Timeout: 50 * time.Millisecond, Interval: 10 * time.Millisecond},
+		Labels:      labels.Everything(),
+		Annotations: labels.Everything(),
+		Namespaces:  labels.Everything(),
+		Logger:      logger,
+	}
+
+	// nothing replaces the deleted pod
+	victims, err := spec.Apply(context.Background(), client, friday)
+	suite.Require().NoError(err)
+	suite.Require().Len(victims, 1)
+	suite.Require().NotNil(victims[0].Recovery)
+	suite.False(victims[0].Recovery.Recovered)
+	suite.True(victims[0].Recovery.SLOExceeded)
+	suite.assertLog(log.WarnLevel, "owner did not recover", log.Fields{"name": "web-1", "owner": "ReplicaSet/web-123"})
+}
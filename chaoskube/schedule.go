@@ -0,0 +1,145 @@
+package chaoskube
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Schedule decides when chaos strikes next
+type Schedule interface {
+	// Next returns the first point in time after the given one at which to run,
+	// or the zero time if the schedule never fires again
+	Next(after time.Time) time.Time
+}
+
+// Interval is a Schedule that fires at a fixed interval
+type Interval time.Duration
+
+func (i Interval) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(i))
+}
+
+// Jitter is a Schedule that fires at a mean interval, each run moved randomly and uniformly by up
+// to Jitter in either direction
+type Jitter struct {
+	Mean   time.Duration
+	Jitter time.Duration
+	// the random source; seed it for deterministic runs
+	Rand *rand.Rand
+}
+
+func (j *Jitter) Next(after time.Time) time.Time {
+	offset := time.Duration(j.Rand.Int63n(2*int64(j.Jitter)+1)) - j.Jitter
+	return after.Add(j.Mean + offset)
+}
+
+// Poisson is a Schedule whose runs arrive as a Poisson process with the given mean interval,
+// that is, with exponentially distributed gaps in between
+type Poisson struct {
+	Mean time.Duration
+	// the random source; seed it for deterministic runs
+	Rand *rand.Rand
+}
+
+func (p *Poisson) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(p.Rand.ExpFloat64() * float64(p.Mean)))
+}
+
+// Clamp keeps the gaps between the runs of another Schedule between Min and Max. A zero Max
+// means there is no upper bound.
+type Clamp struct {
+	Schedule Schedule
+	Min      time.Duration
+	Max      time.Duration
+}
+
+func (c *Clamp) Next(after time.Time) time.Time {
+	next := c.Schedule.Next(after)
+	if next.IsZero() {
+		return next
+	}
+	if gap := next.Sub(after); gap < c.Min {
+		return after.Add(c.Min)
+	} else if c.Max > 0 && gap > c.Max {
+		return after.Add(c.Max)
+	}
+	return next
+}
+
+// Scheduler feeds the fire times of a Schedule into the channel consumed by Chaoskube.Run
+type Scheduler struct {
+	// when to fire
+	Schedule Schedule
+	// the timezone to evaluate the schedule in
+	Timezone *time.Location
+	// an instance of logrus.StdLogger to write log messages to
+	Logger log.FieldLogger
+	// a function to retrieve the current time
+	Now func() time.Time
+
+	mutex   sync.Mutex
+	nextRun time.Time
+}
+
+// NewScheduler returns a Scheduler evaluating the given schedule in the given timezone
+func NewScheduler(schedule Schedule, timezone *time.Location, logger log.FieldLogger) *Scheduler {
+	return &Scheduler{
+		Schedule: schedule,
+		Timezone: timezone,
+		Logger:   logger,
+		Now:      time.Now,
+	}
+}
+
+// Ticks returns a channel that receives a value each time the schedule fires. A tick is only
+// handed over once the receiver is ready for it, so the next run is planned after the previous
+// one has been picked up. The channel stops receiving values when the given context is canceled.
+func (s *Scheduler) Ticks(ctx context.Context) <-chan time.Time {
+	ticks := make(chan time.Time)
+
+	go func() {
+		for {
+			now := s.Now().In(s.Timezone)
+			next := s.Schedule.Next(now)
+			s.setNextRun(next)
+			if next.IsZero() {
+				s.Logger.Error("schedule never fires again")
+				return
+			}
+
+			s.Logger.WithField("nextRun", next.Format(time.RFC3339)).Info("next run scheduled")
+
+			timer := time.NewTimer(next.Sub(now))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case tick := <-timer.C:
+				select {
+				case ticks <- tick:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return ticks
+}
+
+// NextRun returns the point in time the schedule fires next, or the zero time if unknown
+func (s *Scheduler) NextRun() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.nextRun
+}
+
+func (s *Scheduler) setNextRun(next time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.nextRun = next
+}
@@ -0,0 +1,113 @@
+package chaoskube
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/neo-technology/marmoset/util"
+)
+
+func (suite *Suite) TestIntervalNext() {
+	now := ThankGodItsFriday{}.Now()
+	suite.Equal(now.Add(10*time.Minute), Interval(10*time.Minute).Next(now))
+}
+
+// TestSchedulerTicks tests that ticks are delivered and the next run is tracked
+func (suite *Suite) TestSchedulerTicks() {
+	scheduler := NewScheduler(Interval(time.Millisecond), time.UTC, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ticks := scheduler.Ticks(ctx)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-ticks:
+		case <-time.After(1 * time.Minute):
+			suite.FailNow("expected a tick")
+		}
+	}
+
+	suite.False(scheduler.NextRun().IsZero())
+}
+
+// TestSchedulerUsesTimezone tests that cron schedules are evaluated in the configured timezone
+func (suite *Suite) TestSchedulerUsesTimezone() {
+	australia, err := time.LoadLocation("Australia/Brisbane")
+	suite.Require().NoError(err)
+
+	schedule, err := util.ParseCron("0 9 * * *")
+	suite.Require().NoError(err)
+
+	scheduler := NewScheduler(schedule, australia, logger)
+	scheduler.Now = ThankGodItsFriday{}.Now // early Saturday morning in Brisbane
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	scheduler.Ticks(ctx)
+
+	deadline := time.Now().Add(1 * time.Minute)
+	for scheduler.NextRun().IsZero() && time.Now().Before(deadline) {
+		time.Sleep(1 * time.Millisecond)
+	}
+	suite.Equal(time.Date(1869, 9, 25, 9, 0, 0, 0, australia), scheduler.NextRun())
+}
+
+func (suite *Suite) TestJitterStaysWithinBounds() {
+	now := ThankGodItsFriday{}.Now()
+	jitter := &Jitter{Mean: 10 * time.Minute, Jitter: 2 * time.Minute, Rand: rand.New(rand.NewSource(42))}
+
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 1000; i++ {
+		gap := jitter.Next(now).Sub(now)
+		suite.Require().True(gap >= 8*time.Minute && gap <= 12*time.Minute, "gap out of bounds: %s", gap)
+		seen[gap] = true
+	}
+	suite.True(len(seen) > 1, "expected the gaps to vary")
+}
+
+func (suite *Suite) TestPoissonMeanInterval() {
+	now := ThankGodItsFriday{}.Now()
+	poisson := &Poisson{Mean: 10 * time.Minute, Rand: rand.New(rand.NewSource(42))}
+
+	var total time.Duration
+	for i := 0; i < 10000; i++ {
+		total += poisson.Next(now).Sub(now)
+	}
+	mean := total / 10000
+	suite.True(mean > 9*time.Minute && mean < 11*time.Minute, "unexpected mean: %s", mean)
+}
+
+func (suite *Suite) TestSeededSchedulesAreDeterministic() {
+	now := ThankGodItsFriday{}.Now()
+	first := &Poisson{Mean: 10 * time.Minute, Rand: rand.New(rand.NewSource(7))}
+	second := &Poisson{Mean: 10 * time.Minute, Rand: rand.New(rand.NewSource(7))}
+
+	for i := 0; i < 100; i++ {
+		suite.Equal(first.Next(now), second.Next(now))
+	}
+}
+
+func (suite *Suite) TestClamp() {
+	now := ThankGodItsFriday{}.Now()
+
+	for _, tt := range []struct {
+		interval time.Duration
+		min      time.Duration
+		max      time.Duration
+		expected time.Duration
+	}{
+		// within bounds
+		{5 * time.Minute, 1 * time.Minute, 10 * time.Minute, 5 * time.Minute},
+		// too short
+		{30 * time.Second, 1 * time.Minute, 10 * time.Minute, 1 * time.Minute},
+		// too long
+		{1 * time.Hour, 1 * time.Minute, 10 * time.Minute, 10 * time.Minute},
+		// no upper bound
+		{1 * time.Hour, 1 * time.Minute, 0, 1 * time.Hour},
+	} {
+		clamp := &Clamp{Schedule: Interval(tt.interval), Min: tt.min, Max: tt.max}
+		suite.Equal(now.Add(tt.expected), clamp.Next(now))
+	}
+}
@@ -0,0 +1,271 @@
+package chaoskube
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VictimSelector is a strategy for picking the victim among the candidates of a ChaosSpec.
+// Selectors aren't safe for concurrent use, which they needn't be as runs never overlap.
+type VictimSelector interface {
+	// Select returns the index of the victim among the candidates, of which there is at least one
+	Select(candidates []metav1.Object, now time.Time) int
+	// the strategy as ParseVictimSelector takes it
+	String() string
+}
+
+// HitRecorder is a VictimSelector that wants to know which of the candidates it selected were
+// admitted as victims, as opposed to turned down by the blast radius
+type HitRecorder interface {
+	Hit(victim metav1.Object, now time.Time)
+}
+
+// RandomSelector picks uniformly at random
+type RandomSelector struct {
+	// the random source; seed it for deterministic runs
+	Rand *rand.Rand
+}
+
+func (s *RandomSelector) String() string {
+	return "random"
+}
+
+func (s *RandomSelector) Select(candidates []metav1.Object, now time.Time) int {
+	return s.Rand.Intn(len(candidates))
+}
+
+// WeightedSelector picks at random, weighted by the value of an annotation or, lacking that, a
+// label. Candidates without either weigh 1, those with a value that isn't a non-negative number 0.
+// If all weigh 0, they are picked uniformly.
+type WeightedSelector struct {
+	// the annotation or label holding the weight
+	Key string
+	// the random source; seed it for deterministic runs
+	Rand *rand.Rand
+}
+
+func (s *WeightedSelector) Select(candidates []metav1.Object, now time.Time) int {
+	weights := make([]float64, len(candidates))
+	total := 0.0
+	for i, candidate := range candidates {
+		weights[i] = s.weight(candidate)
+		total += weights[i]
+	}
+	if total == 0 {
+		return s.Rand.Intn(len(candidates))
+	}
+
+	pick := s.Rand.Float64() * total
+	for i, weight := range weights {
+		if pick < weight {
+			return i
+		}
+		pick -= weight
+	}
+	// only reached through rounding errors
+	return len(candidates) - 1
+}
+
+func (s *WeightedSelector) String() string {
+	return "weighted=" + s.Key
+}
+
+func (s *WeightedSelector) weight(candidate metav1.Object) float64 {
+	value, ok := candidate.GetAnnotations()[s.Key]
+	if !ok {
+		value, ok = candidate.GetLabels()[s.Key]
+	}
+	if !ok {
+		return 1
+	}
+	weight, err := strconv.ParseFloat(value, 64)
+	if err != nil || weight < 0 {
+		return 0
+	}
+	return weight
+}
+
+// PerOwnerSelector picks an owner uniformly at random, and then one of its candidates, so an
+// owner with many pods isn't hit more often than one with few
+type PerOwnerSelector struct {
+	// the random source; seed it for deterministic runs
+	Rand *rand.Rand
+}
+
+func (s *PerOwnerSelector) String() string {
+	return "per-owner"
+}
+
+func (s *PerOwnerSelector) Select(candidates []metav1.Object, now time.Time) int {
+	owners, byOwner := groupByOwner(candidates)
+	owned := byOwner[owners[s.Rand.Intn(len(owners))]]
+	return owned[s.Rand.Intn(len(owned))]
+}
+
+// LeastRecentlyHitSelector picks among the candidates of the owner hit least recently, so every
+// owner gets chaos regularly. Owners never hit come first; ties are broken randomly.
+type LeastRecentlyHitSelector struct {
+	// the random source; seed it for deterministic runs
+	Rand *rand.Rand
+
+	// when a victim of each owner was last admitted
+	hits map[string]time.Time
+}
+
+func (s *LeastRecentlyHitSelector) String() string {
+	return "least-recently-hit"
+}
+
+func (s *LeastRecentlyHitSelector) Select(candidates []metav1.Object, now time.Time) int {
+	owners, byOwner := groupByOwner(candidates)
+
+	leastRecent := []string{}
+	var leastRecentHit time.Time
+	for _, owner := range owners {
+		hit := s.hits[owner]
+		switch {
+		case len(leastRecent) == 0 || hit.Before(leastRecentHit):
+			leastRecent = []string{owner}
+			leastRecentHit = hit
+		case hit.Equal(leastRecentHit):
+			leastRecent = append(leastRecent, owner)
+		}
+	}
+
+	owned := byOwner[leastRecent[s.Rand.Intn(len(leastRecent))]]
+	return owned[s.Rand.Intn(len(owned))]
+}
+
+func (s *LeastRecentlyHitSelector) Hit(victim metav1.Object, now time.Time) {
+	if s.hits == nil {
+		s.hits = map[string]time.Time{}
+	}
+	s.hits[ownerKey(victim)] = now
+}
+
+// OldestSelector picks the candidate created first
+type OldestSelector struct{}
+
+func (s *OldestSelector) String() string {
+	return "oldest"
+}
+
+func (s *OldestSelector) Select(candidates []metav1.Object, now time.Time) int {
+	oldest := 0
+	for i, candidate := range candidates {
+		if candidate.GetCreationTimestamp().Time.Before(candidates[oldest].GetCreationTimestamp().Time) {
+			oldest = i
+		}
+	}
+	return oldest
+}
+
+// NewestSelector picks the candidate created last
+type NewestSelector struct{}
+
+func (s *NewestSelector) String() string {
+	return "newest"
+}
+
+func (s *NewestSelector) Select(candidates []metav1.Object, now time.Time) int {
+	newest := 0
+	for i, candidate := range candidates {
+		if candidates[newest].GetCreationTimestamp().Time.Before(candidate.GetCreationTimestamp().Time) {
+			newest = i
+		}
+	}
+	return newest
+}
+
+// ParseVictimSelector parses a selection strategy: random, weighted=<annotation or label>,
+// per-owner, least-recently-hit, oldest or newest
+func ParseVictimSelector(strategy string, random *rand.Rand) (VictimSelector, error) {
+	switch {
+	case strategy == "random":
+		return &RandomSelector{Rand: random}, nil
+	case strings.HasPrefix(strategy, "weighted="):
+		key := strings.TrimPrefix(strategy, "weighted=")
+		if key == "" {
+			return nil, fmt.Errorf("Invalid victim selection '%v': must be weighted=<annotation or label>", strategy)
+		}
+		return &WeightedSelector{Key: key, Rand: random}, nil
+	case strategy == "per-owner":
+		return &PerOwnerSelector{Rand: random}, nil
+	case strategy == "least-recently-hit":
+		return &LeastRecentlyHitSelector{Rand: random}, nil
+	case strategy == "oldest":
+		return &OldestSelector{}, nil
+	case strategy == "newest":
+		return &NewestSelector{}, nil
+	}
+	return nil, fmt.Errorf("Invalid victim selection '%v': must be random, weighted=<key>, per-owner, least-recently-hit, oldest or newest", strategy)
+}
+
+// ParseExperimentVictimSelection returns the strategy for the experiment among the selections, each
+// written as <experiment>=<strategy>, or fallback if none is for it
+func ParseExperimentVictimSelection(selections []string, experiment, fallback string) (string, error) {
+	strategy := fallback
+	for _, selection := range selections {
+		parts := strings.SplitN(selection, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return "", fmt.Errorf("Invalid experiment victim selection '%v': must be <experiment>=<strategy>", selection)
+		}
+		if _, err := ParseVictimSelector(parts[1], nil); err != nil {
+			return "", err
+		}
+		if parts[0] == experiment {
+			strategy = parts[1]
+		}
+	}
+	return strategy, nil
+}
+
+// groupByOwner returns the owners of the candidates, in order of appearance, and the indices of
+// the candidates of each. A candidate without a controller is its own owner.
+func groupByOwner(candidates []metav1.Object) ([]string, map[string][]int) {
+	owners := []string{}
+	byOwner := map[string][]int{}
+	for i, candidate := range candidates {
+		owner := ownerKey(candidate)
+		if _, ok := byOwner[owner]; !ok {
+			owners = append(owners, owner)
+		}
+		byOwner[owner] = append(byOwner[owner], i)
+	}
+	return owners, byOwner
+}
+
+// ownerKey identifies the owner of a candidate
+func ownerKey(candidate metav1.Object) string {
+	if controller := metav1.GetControllerOf(candidate); controller != nil {
+		return candidate.GetNamespace() + "/" + controller.Kind + "/" + controller.Name
+	}
+	if candidate.GetUID() == "" {
+		return "name/" + candidate.GetNamespace() + "/" + candidate.GetName()
+	}
+	return "uid/" + string(candidate.GetUID())
+}
+
+// podObjects returns the pods as objects to select from
+func podObjects(pods []v1.Pod) []metav1.Object {
+	objects := make([]metav1.Object, 0, len(pods))
+	for i := range pods {
+		objects = append(objects, &pods[i])
+	}
+	return objects
+}
+
+// nodeObjects returns the nodes as objects to select from
+func nodeObjects(nodes []v1.Node) []metav1.Object {
+	objects := make([]metav1.Object, 0, len(nodes))
+	for i := range nodes {
+		objects = append(objects, &nodes[i])
+	}
+	return objects
+}
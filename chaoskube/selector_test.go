@@ -0,0 +1,174 @@
+package chaoskube
+
+import (
+	"math/rand"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ownedPod returns a pod owned by the given ReplicaSet, created the given time after Friday
+func ownedPod(name, owner string, created time.Duration, annotations map[string]string) v1.Pod {
+	controller := true
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "default",
+			Name:              name,
+			UID:               types.UID(name),
+			Annotations:       annotations,
+			CreationTimestamp: metav1.NewTime(ThankGodItsFriday{}.Now().Add(created)),
+			OwnerReferences:   []metav1.OwnerReference{{Kind: "ReplicaSet", Name: owner, Controller: &controller}},
+		},
+	}
+}
+
+// selectionCounts runs a selector many times and counts how often each pod was picked
+func selectionCounts(selector VictimSelector, pods []v1.Pod, runs int) map[string]int {
+	friday := ThankGodItsFriday{}.Now()
+	counts := map[string]int{}
+	for i := 0; i < runs; i++ {
+		index := selector.Select(podObjects(pods), friday.Add(time.Duration(i)*time.Minute))
+		counts[pods[index].Name]++
+	}
+	return counts
+}
+
+func (suite *Suite) TestRandomSelector() {
+	pods := []v1.Pod{ownedPod("foo", "a", 0, nil), ownedPod("bar", "a", 0, nil)}
+
+	counts := selectionCounts(&RandomSelector{Rand: rand.New(rand.NewSource(1))}, pods, 1000)
+	suite.InDelta(500, counts["foo"], 100)
+	suite.InDelta(500, counts["bar"], 100)
+
+	// the same seed picks the same victims
+	suite.Equal(counts, selectionCounts(&RandomSelector{Rand: rand.New(rand.NewSource(1))}, pods, 1000))
+}
+
+func (suite *Suite) TestWeightedSelector() {
+	pods := []v1.Pod{
+		ownedPod("heavy", "a", 0, map[string]string{"chaos-weight": "3"}),
+		ownedPod("default", "a", 0, nil),
+		ownedPod("never", "a", 0, map[string]string{"chaos-weight": "0"}),
+		ownedPod("invalid", "a", 0, map[string]string{"chaos-weight": "lots"}),
+	}
+	pods[1].Labels = map[string]string{"unrelated": "label"}
+
+	counts := selectionCounts(&WeightedSelector{Key: "chaos-weight", Rand: rand.New(rand.NewSource(1))}, pods, 1000)
+	suite.InDelta(750, counts["heavy"], 100)
+	suite.InDelta(250, counts["default"], 100)
+	suite.Zero(counts["never"])
+	suite.Zero(counts["invalid"])
+
+	// labels count as well, and without any weight all are equal
+	pods = []v1.Pod{ownedPod("foo", "a", 0, nil), ownedPod("bar", "a", 0, nil)}
+	pods[0].Labels = map[string]string{"chaos-weight": "0"}
+	pods[1].Labels = map[string]string{"chaos-weight": "0"}
+	counts = selectionCounts(&WeightedSelector{Key: "chaos-weight", Rand: rand.New(rand.NewSource(1))}, pods, 1000)
+	suite.InDelta(500, counts["foo"], 100)
+}
+
+func (suite *Suite) TestPerOwnerSelector() {
+	pods := []v1.Pod{
+		ownedPod("big-1", "big", 0, nil),
+		ownedPod("big-2", "big", 0, nil),
+		ownedPod("big-3", "big", 0, nil),
+		ownedPod("small-1", "small", 0, nil),
+	}
+
+	counts := selectionCounts(&PerOwnerSelector{Rand: rand.New(rand.NewSource(1))}, pods, 1200)
+	suite.InDelta(600, counts["small-1"], 100)
+	suite.InDelta(200, counts["big-1"], 100)
+}
+
+func (suite *Suite) TestLeastRecentlyHitSelector() {
+	pods := []v1.Pod{
+		ownedPod("a-1", "a", 0, nil),
+		ownedPod("a-2", "a", 0, nil),
+		ownedPod("b-1", "b", 0, nil),
+		ownedPod("c-1", "c", 0, nil),
+	}
+	selector := &LeastRecentlyHitSelector{Rand: rand.New(rand.NewSource(1))}
+	friday := ThankGodItsFriday{}.Now()
+
+	owners := []string{}
+	for i := 0; i < 6; i++ {
+		now := friday.Add(time.Duration(i) * time.Minute)
+		index := selector.Select(podObjects(pods), now)
+		selector.Hit(&pods[index], now)
+		owners = append(owners, pods[index].Name[:1])
+	}
+
+	// every owner gets its turn before any gets a second one, in the same order
+	suite.ElementsMatch([]string{"a", "b", "c"}, owners[:3])
+	suite.Equal(owners[:3], owners[3:])
+}
+
+// TestLeastRecentlyHitSelectorTurnedDown tests that an owner whose victim the blast radius turned
+// down isn't counted as hit
+func (suite *Suite) TestLeastRecentlyHitSelectorTurnedDown() {
+	pods := []v1.Pod{
+		ownedPod("a-1", "a", 0, nil),
+		ownedPod("b-1", "b", 0, nil),
+	}
+	selector := &LeastRecentlyHitSelector{Rand: rand.New(rand.NewSource(1))}
+	friday := ThankGodItsFriday{}.Now()
+
+	selector.Hit(&pods[1], friday)
+	suite.Equal(0, selector.Select(podObjects(pods), friday))
+
+	// a is still the least recently hit after its victim was turned down
+	later := friday.Add(time.Minute)
+	suite.Empty(pickVictims(podObjects(pods), VictimCount{Count: 1}, selector, func(i int) bool { return false }, nil, later))
+	suite.Equal(0, selector.Select(podObjects(pods), later))
+
+	// but not once one was admitted
+	suite.Equal([]int{0}, pickVictims(podObjects(pods), VictimCount{Count: 1}, selector, func(i int) bool { return true }, nil, later))
+	suite.Equal(1, selector.Select(podObjects(pods), later))
+}
+
+func (suite *Suite) TestOldestAndNewestSelector() {
+	pods := []v1.Pod{
+		ownedPod("middle", "a", time.Hour, nil),
+		ownedPod("oldest", "a", 0, nil),
+		ownedPod("newest", "a", 2*time.Hour, nil),
+	}
+	friday := ThankGodItsFriday{}.Now()
+
+	suite.Equal(1, (&OldestSelector{}).Select(podObjects(pods), friday))
+	suite.Equal(2, (&NewestSelector{}).Select(podObjects(pods), friday))
+}
+
+func (suite *Suite) TestParseVictimSelector() {
+	random := rand.New(rand.NewSource(1))
+	for _, strategy := range []string{"random", "weighted=chaos-weight", "per-owner", "least-recently-hit", "oldest", "newest"} {
+		selector, err := ParseVictimSelector(strategy, random)
+		suite.Require().NoError(err)
+		suite.Equal(strategy, selector.String())
+	}
+
+	for _, invalid := range []string{"", "weighted=", "fairest"} {
+		_, err := ParseVictimSelector(invalid, random)
+		suite.Error(err, invalid)
+	}
+}
+
+func (suite *Suite) TestParseExperimentVictimSelection() {
+	selections := []string{"kill-frontend=least-recently-hit", "kill-backend=weighted=chaos-weight"}
+
+	for experiment, expected := range map[string]string{
+		"kill-frontend": "least-recently-hit",
+		"kill-backend":  "weighted=chaos-weight",
+		"drain":         "random",
+	} {
+		strategy, err := ParseExperimentVictimSelection(selections, experiment, "random")
+		suite.Require().NoError(err)
+		suite.Equal(expected, strategy, experiment)
+	}
+
+	for _, invalid := range []string{"least-recently-hit", "=oldest", "kill-frontend=fairest"} {
+		_, err := ParseExperimentVictimSelection([]string{invalid}, "kill-frontend", "random")
+		suite.Error(err, invalid)
+	}
+}
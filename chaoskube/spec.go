@@ -1,66 +1,232 @@
 package chaoskube
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/neo-technology/marmoset/chaoskube/action"
 	log "github.com/sirupsen/logrus"
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
 	clientset "k8s.io/client-go/kubernetes"
 	"math/rand"
+	"strings"
 	"time"
 )
 
 type ChaosSpec interface {
 	// Ran once when the chaos monkey starts; for any one-time initialization
-	Init(k8sclient clientset.Interface) error
-	Apply(k8sclient clientset.Interface, now time.Time) error
+	Init(ctx context.Context, k8sclient clientset.Interface) error
+	// Picks victims and imbues chaos in them; returns the victims, or none if there were none.
+	// Cancelling the context stops the actions as soon as they can.
+	Apply(ctx context.Context, k8sclient clientset.Interface, now time.Time) ([]*Victim, error)
+	// Lists what Apply would currently pick from, without doing anything
+	Candidates(k8sclient clientset.Interface, now time.Time) (*CandidateReport, error)
 }
 
+// Victim identifies what a ChaosSpec imbued chaos in
+type Victim struct {
+	// "pod" or "node"
+	Kind      string    `json:"kind"`
+	Namespace string    `json:"namespace,omitempty"`
+	Name      string    `json:"name"`
+	UID       types.UID `json:"uid,omitempty"`
+	// the controller of a pod, as Kind/name
+	Owner string `json:"owner,omitempty"`
+	// the node a pod ran on, or the node itself
+	Node string `json:"node,omitempty"`
+	// the name of the action applied to the victim
+	Action string `json:"action"`
+	// why the action failed on this victim, if it did
+	Error string `json:"error,omitempty"`
+	// how the owner of a pod recovered, if that was measured
+	Recovery *RecoveryResult `json:"recovery,omitempty"`
+}
+
+// String identifies the victim as kind and name, like "pod default/foo"
+func (v *Victim) String() string {
+	if v.Namespace == "" {
+		return v.Kind + " " + v.Name
+	}
+	return v.Kind + " " + v.Namespace + "/" + v.Name
+}
+
+func podVictim(pod v1.Pod, action string) *Victim {
+	victim := &Victim{
+		Kind:      KindPod,
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		UID:       pod.UID,
+		Node:      pod.Spec.NodeName,
+		Action:    action,
+	}
+	if owner := ownerReference(pod); owner != nil {
+		victim.Owner = owner.Kind + "/" + owner.Name
+	}
+	return victim
+}
+
+func nodeVictim(node v1.Node, action string) *Victim {
+	return &Victim{Kind: KindNode, Name: node.Name, UID: node.UID, Node: node.Name, Action: action}
+}
+
+const (
+	KindPod  = "pod"
+	KindNode = "node"
+)
+
 // == Node chaos ==
 
 type NodeChaosSpec struct {
 	Action action.NodeAction
+	// the experiment to record Events for; optional
+	Experiment *Experiment
+	// limits on how many nodes chaos is imbued in; optional
+	BlastRadius *BlastRadius
+	// picks the victim among the candidates; optional, uniformly random by default
+	VictimSelector VictimSelector
+	// how many nodes to pick per run; one by default
+	Count VictimCount
+	// how many nodes to imbue chaos in at a time; one by default
+	Concurrency int
+	// keeps the nodes in memory; optional, without one they are listed on every run
+	Cache *ClusterCache
+	// how long the action may take on each node; no limit if zero
+	Timeout time.Duration
 	// an instance of logrus.StdLogger to write log messages to
 	Logger log.FieldLogger
 }
 
-func (s *NodeChaosSpec) Init(k8sclient clientset.Interface) error {
-	return s.Action.Init(k8sclient)
+func (s *NodeChaosSpec) Init(ctx context.Context, k8sclient clientset.Interface) error {
+	if s.Cache != nil {
+		// watches until the chaos monkey stops
+		if err := s.Cache.Start(ctx); err != nil {
+			return err
+		}
+	}
+	if s.BlastRadius != nil {
+		if err := s.BlastRadius.Init(time.Now()); err != nil {
+			return err
+		}
+	}
+	return s.Action.Init(ctx, k8sclient)
 }
 
-func (s *NodeChaosSpec) Apply(client clientset.Interface, now time.Time) error {
-	candidates, err := s.candidates(client, now)
+func (s *NodeChaosSpec) Apply(ctx context.Context, client clientset.Interface, now time.Time) ([]*Victim, error) {
+	candidates, _, err := s.candidates(client, now)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(candidates) == 0 {
 		s.Logger.Debugf(msgVictimNotFound)
-		return nil
+		return nil, nil
+	}
+
+	picked := pickVictims(nodeObjects(candidates), s.Count, s.VictimSelector, func(i int) bool {
+		return admitVictim(s.BlastRadius, nodeVictim(candidates[i], s.Action.Name()), now)
+	}, nil, now)
+
+	victims := make([]*Victim, len(picked))
+	for j, i := range picked {
+		s.Logger.WithFields(log.Fields{
+			"namespace": candidates[i].Namespace,
+			"name":      candidates[i].Name,
+		}).Info(s.Action.Name())
+
+		victims[j] = nodeVictim(candidates[i], s.Action.Name())
+		if s.Experiment != nil {
+			s.Experiment.applying(victims[j])
+		}
+	}
+
+	errs := make([]error, len(picked))
+	applyAll(len(picked), s.Concurrency, func(j int) {
+		victim := candidates[picked[j]]
+		actionCtx, cancel := withTimeout(ctx, s.Timeout)
+		defer cancel()
+		errs[j] = s.Action.ApplyToNode(actionCtx, client, &victim)
+		if s.Experiment != nil {
+			s.Experiment.nodeApplied(&victim, victims[j], errs[j])
+		}
+	})
+
+	return victims, victimsApplied(s.Logger, victims, errs)
+}
+
+func (s *NodeChaosSpec) Candidates(client clientset.Interface, now time.Time) (*CandidateReport, error) {
+	nodes, report, err := s.candidates(client, now)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range nodes {
+		report.Candidates = append(report.Candidates, Candidate{
+			Kind:   KindNode,
+			Name:   node.Name,
+			Reason: s.reason(),
+		})
+	}
+	return report, nil
+}
+
+func (s *NodeChaosSpec) candidates(client clientset.Interface, now time.Time) ([]v1.Node, *CandidateReport, error) {
+	nodes, err := s.listNodes(client)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	index := rand.Intn(len(candidates))
-	victim := candidates[index]
+	report := newCandidateReport(len(nodes))
 
-	s.Logger.WithFields(log.Fields{
-		"namespace": victim.Namespace,
-		"name":      victim.Name,
-	}).Info(s.Action.Name())
+	if s.BlastRadius != nil {
+		nodes = report.removedNodes(FilterBlastRadius, nodes, s.BlastRadius.filterNodes(nodes, s.Action.Name(), now))
+	}
 
-	return s.Action.ApplyToNode(client, &victim)
+	return nodes, report, nil
 }
 
-func (s *NodeChaosSpec) candidates(client clientset.Interface, now time.Time) ([]v1.Node, error) {
+// listNodes lists all nodes, from the cache if there is one
+func (s *NodeChaosSpec) listNodes(client clientset.Interface) ([]v1.Node, error) {
+	if s.Cache != nil {
+		return s.Cache.Nodes(labels.Everything())
+	}
+
 	nodeList, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
+	return nodeList.Items, nil
+}
+
+// reason tells why a node passed the filters
+func (s *NodeChaosSpec) reason() string {
+	if s.BlastRadius != nil {
+		return "within blast radius"
+	}
+	return "every node is a candidate"
+}
 
-	nodes := nodeList.Items
-	return nodes, nil
+// MarshalJSON describes the spec for the status API
+func (s *NodeChaosSpec) MarshalJSON() ([]byte, error) {
+	description := map[string]interface{}{
+		"target": KindNode,
+		"action": s.Action.Name(),
+	}
+	if s.BlastRadius != nil {
+		description["limits"] = s.BlastRadius.Limits
+	}
+	if s.VictimSelector != nil {
+		description["victimSelector"] = s.VictimSelector.String()
+	}
+	if s.Timeout > 0 {
+		description["timeout"] = s.Timeout.String()
+	}
+	description["victims"] = s.Count.String()
+	return json.Marshal(description)
 }
 
 func NewNodeChaosSpec(action action.NodeAction, logger log.FieldLogger) ChaosSpec {
@@ -74,62 +240,345 @@ func NewNodeChaosSpec(action action.NodeAction, logger log.FieldLogger) ChaosSpe
 
 type PodChaosSpec struct {
 	Action action.PodAction
+	// the experiment to record Events for; optional
+	Experiment *Experiment
+	// limits on how many pods chaos is imbued in; optional
+	BlastRadius *BlastRadius
+	// picks the victim among the candidates; optional, uniformly random by default
+	VictimSelector VictimSelector
+	// how many pods to pick per run; one by default
+	Count VictimCount
+	// how many pods to imbue chaos in at a time; one by default
+	Concurrency int
+	// keeps pods, nodes and namespaces in memory; optional, without one they are listed on every
+	// run
+	Cache *ClusterCache
+	// how long the action may take on each pod; no limit if zero
+	Timeout time.Duration
+	// measures how long the owners of victims take to recover; optional
+	Recovery *Recovery
+	// restricts chaos to pods that opted in through annotations; optional
+	OptIn *OptIn
 	// a label selector which restricts the pods to choose from
 	Labels labels.Selector
 	// an annotation selector which restricts the pods to choose from
 	Annotations labels.Selector
 	// a namespace selector which restricts the pods to choose from
 	Namespaces labels.Selector
+	// a selector of the labels of namespaces which restricts the pods to choose from; optional
+	NamespaceLabels labels.Selector
+	// looks up namespaces for NamespaceLabels and OptIn; optional, without one they are looked up
+	// on every run
+	NamespaceCache *NamespaceCache
+	// names of the nodes the pods to choose from must run on; any if empty
+	NodeNames []string
+	// a selector of the labels of the nodes the pods to choose from must run on; optional
+	NodeLabels labels.Selector
+	// restricts the pods to choose from by readiness, restarts, QoS class, priority and images;
+	// optional
+	Filter *PodFilter
+	// restricts the pods to choose from by their owners; optional
+	Owners *OwnerFilter
+	// resolves the owners of pods for Owners; optional, without one they are looked up on every run
+	OwnerCache *OwnerCache
 	// minimum age of pods to consider
 	MinimumAge time.Duration
 	// an instance of logrus.StdLogger to write log messages to
 	Logger log.FieldLogger
 }
 
-func (s *PodChaosSpec) Init(k8sclient clientset.Interface) error {
-	return s.Action.Init(k8sclient)
+func (s *PodChaosSpec) Init(ctx context.Context, k8sclient clientset.Interface) error {
+	if s.Cache != nil {
+		// watches until the chaos monkey stops
+		if err := s.Cache.Start(ctx); err != nil {
+			return err
+		}
+	}
+	if s.OptIn != nil {
+		// opt-in mode counts the victims of a day for marmoset/max-per-day
+		if s.BlastRadius == nil {
+			s.BlastRadius = &BlastRadius{}
+		}
+		if s.BlastRadius.Retention < day {
+			s.BlastRadius.Retention = day
+		}
+	}
+	if s.BlastRadius != nil {
+		if err := s.BlastRadius.Init(time.Now()); err != nil {
+			return err
+		}
+	}
+	return s.Action.Init(ctx, k8sclient)
 }
 
-func (s *PodChaosSpec) Apply(client clientset.Interface, now time.Time) error {
-	candidates, err := s.candidates(client, now)
+func (s *PodChaosSpec) Apply(ctx context.Context, client clientset.Interface, now time.Time) ([]*Victim, error) {
+	candidates, _, err := s.candidates(client, now)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(candidates) == 0 {
 		s.Logger.Debugf(msgVictimNotFound)
-		return nil
+		return nil, nil
 	}
 
-	index := rand.Intn(len(candidates))
-	victim := candidates[index]
+	// a Deployment in the middle of a rollout is one owner, not one per ReplicaSet
+	objects, err := ownedObjects(client, candidates, s.ownerCache(), now)
+	if err != nil {
+		return nil, err
+	}
+	picked := pickVictims(objects, s.Count, s.VictimSelector, func(i int) bool {
+		return s.admit(client, candidates[i], now)
+	}, func(group []int) int {
+		return ownerReplicas(client, candidates[group[0]].Namespace, metav1.GetControllerOf(objects[group[0]]), len(group), s.Logger)
+	}, now)
+
+	victims := make([]*Victim, len(picked))
+	for j, i := range picked {
+		s.Logger.WithFields(log.Fields{
+			"namespace": candidates[i].Namespace,
+			"name":      candidates[i].Name,
+		}).Info(s.Action.Name())
+
+		victims[j] = podVictim(candidates[i], s.Action.Name())
+		if s.Experiment != nil {
+			s.Experiment.applying(victims[j])
+		}
+	}
 
-	s.Logger.WithFields(log.Fields{
-		"namespace": victim.Namespace,
-		"name":      victim.Name,
-	}).Info(s.Action.Name())
+	errs := make([]error, len(picked))
+	applyAll(len(picked), s.Concurrency, func(j int) {
+		victim := candidates[picked[j]]
+		actionCtx, cancel := withTimeout(ctx, s.Timeout)
+		defer cancel()
+		errs[j] = s.Action.ApplyToPod(actionCtx, victim)
+		if s.Experiment != nil {
+			s.Experiment.podApplied(client, victim, victims[j], errs[j])
+		}
+		if s.Recovery != nil && errs[j] == nil {
+			victims[j].Recovery = s.Recovery.measure(ctx, client, victim, s.Action.Name(), s.Logger)
+		}
+	})
 
-	return s.Action.ApplyToPod(victim)
+	return victims, victimsApplied(s.Logger, victims, errs)
 }
 
-func (s *PodChaosSpec) candidates(client clientset.Interface, now time.Time) ([]v1.Pod, error) {
-	listOptions := metav1.ListOptions{LabelSelector: s.Labels.String()}
+// admit tells whether a pod may still be picked, given the victims picked before it in this run
+func (s *PodChaosSpec) admit(client clientset.Interface, pod v1.Pod, now time.Time) bool {
+	if s.OptIn != nil && s.BlastRadius != nil {
+		// marmoset/max-per-day counts the victims picked before
+		optedIn, err := s.OptIn.filterPods(client, []v1.Pod{pod}, s.namespaceCache(), s.BlastRadius, now, s.Logger)
+		if err != nil || len(optedIn) == 0 {
+			return false
+		}
+	}
+	return admitVictim(s.BlastRadius, podVictim(pod, s.Action.Name()), now)
+}
 
-	podList, err := client.CoreV1().Pods(v1.NamespaceAll).List(listOptions)
+func (s *PodChaosSpec) Candidates(client clientset.Interface, now time.Time) (*CandidateReport, error) {
+	pods, report, err := s.candidates(client, now)
 	if err != nil {
 		return nil, err
 	}
 
-	pods, err := filterByNamespaces(podList.Items, s.Namespaces)
+	for _, pod := range pods {
+		report.Candidates = append(report.Candidates, Candidate{
+			Kind:      KindPod,
+			Namespace: pod.Namespace,
+			Name:      pod.Name,
+			Reason:    s.reason(pod, now),
+		})
+	}
+	return report, nil
+}
+
+// candidates lists the pods matching the label selector and runs them through the filters,
+// counting how many each filter removes
+func (s *PodChaosSpec) candidates(client clientset.Interface, now time.Time) ([]v1.Pod, *CandidateReport, error) {
+	nodes, err := s.nodes(client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pods, err := s.listPods(client, nodes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := newCandidateReport(len(pods))
+
+	if nodes != nil {
+		pods = report.removed(FilterNode, pods, filterByNodes(pods, nodes))
+	}
+
+	filtered, err := filterByNamespaces(pods, s.Namespaces)
+	if err != nil {
+		return nil, nil, err
+	}
+	pods = report.removed(FilterNamespace, pods, filtered)
+
+	namespaceCache := s.namespaceCache()
+	if s.NamespaceLabels != nil && !s.NamespaceLabels.Empty() {
+		filtered, err := filterByNamespaceLabels(client, pods, s.NamespaceLabels, namespaceCache, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		pods = report.removed(FilterNamespaceLabels, pods, filtered)
+	}
+
+	pods = report.removed(FilterAnnotation, pods, filterByAnnotations(pods, s.Annotations))
+	if s.OptIn != nil {
+		blastRadius := s.BlastRadius
+		if blastRadius == nil {
+			// not initialized, so there were no victims yet
+			blastRadius = &BlastRadius{}
+		}
+		optedIn, err := s.OptIn.filterPods(client, pods, namespaceCache, blastRadius, now, s.Logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		pods = report.removed(FilterOptIn, pods, optedIn)
+	}
+	pods = report.removed(FilterPhase, pods, filterByPhase(pods, v1.PodRunning))
+	if s.Filter != nil {
+		pods = s.Filter.filterPods(pods, report)
+	}
+	pods = report.removed(FilterMinimumAge, pods, filterByMinimumAge(pods, s.MinimumAge, now))
+	if s.Owners != nil && !s.Owners.Empty() {
+		owned, err := s.Owners.filterPods(client, pods, s.ownerCache(), now)
+		if err != nil {
+			return nil, nil, err
+		}
+		pods = report.removed(FilterOwner, pods, owned)
+	}
+	if s.BlastRadius != nil {
+		pods = report.removed(FilterBlastRadius, pods, s.BlastRadius.filterPods(pods, s.Action.Name(), now))
+	}
+
+	return pods, report, nil
+}
+
+// listPods lists the pods matching the label selector, from the cache if there is one
+func (s *PodChaosSpec) listPods(client clientset.Interface, nodes []string) ([]v1.Pod, error) {
+	if s.Cache != nil {
+		return s.Cache.Pods(s.Labels)
+	}
+
+	listOptions := metav1.ListOptions{LabelSelector: s.Labels.String()}
+	if len(nodes) == 1 {
+		// let the API server do the filtering
+		listOptions.FieldSelector = fields.SelectorFromSet(fields.Set{"spec.nodeName": nodes[0]}).String()
+	}
+
+	podList, err := client.CoreV1().Pods(v1.NamespaceAll).List(listOptions)
 	if err != nil {
 		return nil, err
 	}
+	return podList.Items, nil
+}
 
-	pods = filterByAnnotations(pods, s.Annotations)
-	pods = filterByPhase(pods, v1.PodRunning)
-	pods = filterByMinimumAge(pods, s.MinimumAge, now)
+// namespaceCache returns the cache to look up namespaces with
+func (s *PodChaosSpec) namespaceCache() *NamespaceCache {
+	if s.Cache != nil {
+		return &NamespaceCache{Cluster: s.Cache}
+	}
+	if s.NamespaceCache == nil {
+		// still look up each namespace only once per run
+		return &NamespaceCache{}
+	}
+	return s.NamespaceCache
+}
+
+// ownerCache returns the cache to look up owners with
+func (s *PodChaosSpec) ownerCache() *OwnerCache {
+	if s.OwnerCache == nil {
+		// still look up each owner only once
+		return &OwnerCache{}
+	}
+	return s.OwnerCache
+}
+
+// reason tells why a pod passed the filters
+func (s *PodChaosSpec) reason(pod v1.Pod, now time.Time) string {
+	reasons := []string{}
+	if !s.Labels.Empty() {
+		reasons = append(reasons, fmt.Sprintf("labels match '%s'", s.Labels))
+	}
+	if len(s.NodeNames) > 0 {
+		reasons = append(reasons, fmt.Sprintf("node %s is one of %s", pod.Spec.NodeName, strings.Join(s.NodeNames, ",")))
+	}
+	if s.NodeLabels != nil && !s.NodeLabels.Empty() {
+		reasons = append(reasons, fmt.Sprintf("labels of node %s match '%s'", pod.Spec.NodeName, s.NodeLabels))
+	}
+	if !s.Namespaces.Empty() {
+		reasons = append(reasons, fmt.Sprintf("namespace %s matches '%s'", pod.Namespace, s.Namespaces))
+	}
+	if s.NamespaceLabels != nil && !s.NamespaceLabels.Empty() {
+		reasons = append(reasons, fmt.Sprintf("namespace labels match '%s'", s.NamespaceLabels))
+	}
+	if !s.Annotations.Empty() {
+		reasons = append(reasons, fmt.Sprintf("annotations match '%s'", s.Annotations))
+	}
+	if s.OptIn != nil {
+		reasons = append(reasons, "opted in")
+	}
+	reasons = append(reasons, fmt.Sprintf("phase is %s", pod.Status.Phase))
+	if s.Filter != nil {
+		reasons = append(reasons, s.Filter.reasons(pod)...)
+	}
+	if s.MinimumAge > 0 {
+		age := now.Sub(pod.CreationTimestamp.Time).Truncate(time.Second)
+		reasons = append(reasons, fmt.Sprintf("age %s is over %s", age, s.MinimumAge))
+	}
+	if s.Owners != nil && !s.Owners.Empty() {
+		reasons = append(reasons, s.Owners.String())
+	}
+	if s.BlastRadius != nil && len(s.BlastRadius.Limits) > 0 {
+		reasons = append(reasons, "within blast radius")
+	}
+	return strings.Join(reasons, ", ")
+}
 
-	return pods, nil
+// MarshalJSON describes the spec for the status API
+func (s *PodChaosSpec) MarshalJSON() ([]byte, error) {
+	description := map[string]interface{}{
+		"target":      KindPod,
+		"action":      s.Action.Name(),
+		"labels":      s.Labels.String(),
+		"annotations": s.Annotations.String(),
+		"namespaces":  s.Namespaces.String(),
+		"minimumAge":  s.MinimumAge.String(),
+		"optIn":       s.OptIn != nil,
+	}
+	if s.NamespaceLabels != nil {
+		description["namespaceLabels"] = s.NamespaceLabels.String()
+	}
+	if len(s.NodeNames) > 0 {
+		description["nodeNames"] = s.NodeNames
+	}
+	if s.NodeLabels != nil {
+		description["nodeLabels"] = s.NodeLabels.String()
+	}
+	if s.Filter != nil {
+		description["filter"] = s.Filter
+	}
+	if s.Owners != nil && !s.Owners.Empty() {
+		description["owners"] = s.Owners
+	}
+	if s.BlastRadius != nil && len(s.BlastRadius.Limits) > 0 {
+		description["limits"] = s.BlastRadius.Limits
+	}
+	if s.VictimSelector != nil {
+		description["victimSelector"] = s.VictimSelector.String()
+	}
+	if s.Timeout > 0 {
+		description["timeout"] = s.Timeout.String()
+	}
+	if s.Recovery != nil {
+		description["recovery"] = map[string]string{"timeout": s.Recovery.Timeout.String(), "slo": s.Recovery.SLO.String()}
+	}
+	description["victims"] = s.Count.String()
+	return json.Marshal(description)
 }
 
 func NewPodChaosSpec(action action.PodAction, labels, annotations, namespaces labels.Selector, minimumAge time.Duration,
@@ -144,6 +593,23 @@ func NewPodChaosSpec(action action.PodAction, labels, annotations, namespaces la
 	}
 }
 
+// withTimeout returns a context that is done after the timeout, unless it is zero
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// selectVictim returns the index of the victim among the candidates, picked by the selector if
+// there is one, or else uniformly at random
+func selectVictim(selector VictimSelector, candidates []metav1.Object, now time.Time) int {
+	if selector == nil {
+		return rand.Intn(len(candidates))
+	}
+	return selector.Select(candidates, now)
+}
+
 // filterByNamespaces filters a list of pods by a given namespace selector.
 func filterByNamespaces(pods []v1.Pod, namespaces labels.Selector) ([]v1.Pod, error) {
 	// empty filter returns original list
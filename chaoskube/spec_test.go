@@ -1,8 +1,11 @@
 package chaoskube_test
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"github.com/neo-technology/marmoset/chaoskube"
+	"github.com/neo-technology/marmoset/chaoskube/action"
 	"github.com/neo-technology/marmoset/util"
 	"github.com/sirupsen/logrus/hooks/test"
 	"k8s.io/api/core/v1"
@@ -11,6 +14,9 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -99,7 +105,7 @@ func TestPodChaos(t *testing.T) {
 
 			for i := 0; i < 1000; i++ {
 				// When
-				err := spec.Apply(client, now)
+				_, err := spec.Apply(context.Background(), client, now)
 
 				if err != nil {
 					t.Fatalf("Spec application failed: %s", err)
@@ -154,7 +160,7 @@ func TestNodeChaos(t *testing.T) {
 
 			for i := 0; i < 1000; i++ {
 				// When
-				err := spec.Apply(client, now)
+				_, err := spec.Apply(context.Background(), client, now)
 
 				if err != nil {
 					t.Fatalf("Spec application failed: %s", err)
@@ -179,12 +185,220 @@ func TestNodeChaos(t *testing.T) {
 	}
 }
 
+func TestPodCandidates(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		pod("A", namespace("team"), annotation("chaos", "true"), age(2*time.Hour)),
+		pod("B", annotation("chaos", "true"), age(2*time.Hour)),
+		pod("C", namespace("team"), age(2*time.Hour)),
+		pod("D", namespace("team"), annotation("chaos", "true"), age(2*time.Hour), phase(v1.PodPending)),
+		pod("E", namespace("team"), annotation("chaos", "true")),
+	)
+	spec := chaoskube.NewPodChaosSpec(&recordPodAction{}, selector(""), selector("chaos=true"),
+		selector("team"), 1*time.Hour, logger)
+
+	report, err := spec.Candidates(client, now)
+	if err != nil {
+		t.Fatalf("Listing candidates failed: %s", err)
+	}
+
+	expectedCandidates := []chaoskube.Candidate{{
+		Kind:      chaoskube.KindPod,
+		Namespace: "team",
+		Name:      "A",
+		Reason:    "namespace team matches 'team', annotations match 'chaos=true', phase is Running, age 2h0m0s is over 1h0m0s",
+	}}
+	if !reflect.DeepEqual(expectedCandidates, report.Candidates) {
+		t.Errorf("Expected candidates %v, got %v", expectedCandidates, report.Candidates)
+	}
+
+	expectedFilters := []chaoskube.FilterCount{
+		{Filter: chaoskube.FilterNamespace, Removed: 1},
+		{Filter: chaoskube.FilterAnnotation, Removed: 1},
+		{Filter: chaoskube.FilterPhase, Removed: 1},
+		{Filter: chaoskube.FilterMinimumAge, Removed: 1},
+	}
+	if report.Listed != 5 || !reflect.DeepEqual(expectedFilters, report.Filters) {
+		t.Errorf("Expected 5 listed and filters %v, got %d and %v", expectedFilters, report.Listed, report.Filters)
+	}
+
+	// listing candidates doesn't touch them
+	if actions := client.Fake.Actions(); len(actions) != 1 || actions[0].GetVerb() != "list" {
+		t.Errorf("Expected a single list action, got %v", actions)
+	}
+}
+
+func TestNodeCandidates(t *testing.T) {
+	client := fake.NewSimpleClientset(node("A"), node("B"))
+	spec := chaoskube.NewNodeChaosSpec(&recordNodeAction{}, logger)
+
+	report, err := spec.Candidates(client, now)
+	if err != nil {
+		t.Fatalf("Listing candidates failed: %s", err)
+	}
+	if report.Listed != 2 || len(report.Candidates) != 2 {
+		t.Errorf("Expected 2 nodes listed and 2 candidates, got %d and %v", report.Listed, report.Candidates)
+	}
+}
+
+func TestCandidateReportPrint(t *testing.T) {
+	report := &chaoskube.CandidateReport{
+		Listed:     2,
+		Filters:    []chaoskube.FilterCount{{Filter: chaoskube.FilterPhase, Removed: 1}},
+		Candidates: []chaoskube.Candidate{{Kind: "pod", Namespace: "default", Name: "A", Reason: "phase is Running"}},
+	}
+
+	out := &bytes.Buffer{}
+	if err := report.Print(out); err != nil {
+		t.Fatalf("Printing failed: %s", err)
+	}
+
+	expected := "KIND  NAMESPACE  NAME  REASON\n" +
+		"pod   default    A     phase is Running\n" +
+		"\n" +
+		"listed            2\n" +
+		"removed by phase  1\n" +
+		"candidates        1\n"
+	if out.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestPodChaosRecordsEvents(t *testing.T) {
+	for _, testCase := range []struct {
+		name           string
+		newAction      func(kubernetes.Interface) action.PodAction
+		expectedObject string
+	}{
+		{
+			name:           "Event on the victim while it's still there",
+			newAction:      func(kubernetes.Interface) action.PodAction { return &recordPodAction{} },
+			expectedObject: "Pod/A",
+		},
+		{
+			name:           "Event on the owner once the victim is gone",
+			newAction:      func(client kubernetes.Interface) action.PodAction { return action.NewDeletePodAction(client) },
+			expectedObject: "ReplicaSet/A-1234",
+		},
+	} {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(pod("A", ownedBy("ReplicaSet", "A-1234")))
+			recorder := newObjectRecorder()
+			spec := &chaoskube.PodChaosSpec{
+				Action:      tc.newAction(client),
+				Experiment:  chaoskube.NewExperiment("kill-a", "marmoset-0", recorder),
+				Labels:      labels.Everything(),
+				Annotations: labels.Everything(),
+				Namespaces:  labels.Everything(),
+				Logger:      logger,
+			}
+
+			if _, err := spec.Apply(context.Background(), client, now); err != nil {
+				t.Fatalf("Spec application failed: %s", err)
+			}
+
+			object, event := recorder.next(t)
+			if object != tc.expectedObject {
+				t.Errorf("Expected Event on %s, got %s", tc.expectedObject, object)
+			}
+			if !strings.HasPrefix(event, "Normal "+chaoskube.ReasonChaosApplied+" ") {
+				t.Errorf("Unexpected Event: %s", event)
+			}
+			for _, expected := range []string{spec.Action.Name(), "kill-a", "marmoset-0"} {
+				if !strings.Contains(event, expected) {
+					t.Errorf("Expected Event message to contain %s, got: %s", expected, event)
+				}
+			}
+		})
+	}
+}
+
+func TestEventRecorderCreatesEvents(t *testing.T) {
+	client := fake.NewSimpleClientset(pod("A", ownedBy("ReplicaSet", "A-1234")))
+	spec := &chaoskube.PodChaosSpec{
+		Action:      action.NewDeletePodAction(client),
+		Experiment:  chaoskube.NewExperiment("kill-a", "marmoset-0", chaoskube.NewEventRecorder(client, "marmoset-0", logger)),
+		Labels:      labels.Everything(),
+		Annotations: labels.Everything(),
+		Namespaces:  labels.Everything(),
+		Logger:      logger,
+	}
+
+	if _, err := spec.Apply(context.Background(), client, now); err != nil {
+		t.Fatalf("Spec application failed: %s", err)
+	}
+
+	event := awaitEvent(t, client, "default")
+	if object := event.InvolvedObject.Kind + "/" + event.InvolvedObject.Name; object != "ReplicaSet/A-1234" {
+		t.Errorf("Expected Event on ReplicaSet/A-1234, got %s", object)
+	}
+	if event.Type != v1.EventTypeNormal || event.Reason != chaoskube.ReasonChaosApplied || event.Source.Component != "marmoset" || event.Source.Host != "marmoset-0" {
+		t.Errorf("Unexpected Event: %v", event)
+	}
+}
+
+func TestNodeChaosRecordsEvents(t *testing.T) {
+	client := fake.NewSimpleClientset(node("A"))
+	recorder := newObjectRecorder()
+	spec := &chaoskube.NodeChaosSpec{
+		Action:     &recordNodeAction{},
+		Experiment: chaoskube.NewExperiment("drain", "marmoset-0", recorder),
+		Logger:     logger,
+	}
+
+	if _, err := spec.Apply(context.Background(), client, now); err != nil {
+		t.Fatalf("Spec application failed: %s", err)
+	}
+
+	if object, _ := recorder.next(t); object != "Node/A" {
+		t.Errorf("Expected Event on Node/A, got %s", object)
+	}
+}
+
+func TestPodChaosSpecTimeout(t *testing.T) {
+	client := fake.NewSimpleClientset(pod("A"))
+	spec := &chaoskube.PodChaosSpec{
+		Action:      &blockingPodAction{},
+		Timeout:     10 * time.Millisecond,
+		Labels:      labels.Everything(),
+		Annotations: labels.Everything(),
+		Namespaces:  labels.Everything(),
+		Logger:      logger,
+	}
+
+	victims, err := spec.Apply(context.Background(), client, now)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected the action to time out, got: %v", err)
+	}
+	if len(victims) != 1 || victims[0].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("Expected the victim to record the timeout, got: %v", victims)
+	}
+}
+
+func TestPodChaosSpecCancelled(t *testing.T) {
+	client := fake.NewSimpleClientset(pod("A"))
+	spec := &chaoskube.PodChaosSpec{
+		Action:      &blockingPodAction{},
+		Labels:      labels.Everything(),
+		Annotations: labels.Everything(),
+		Namespaces:  labels.Everything(),
+		Logger:      logger,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	if _, err := spec.Apply(ctx, client, now); err != context.Canceled {
+		t.Fatalf("Expected the action to be cancelled, got: %v", err)
+	}
+}
+
 func TestNodeSpecInitDelegatesToActionInit(t *testing.T) {
 	client := fake.NewSimpleClientset()
 	action := &recordNodeAction{}
 	spec := chaoskube.NodeChaosSpec{Action: action}
 
-	err := spec.Init(client)
+	err := spec.Init(context.Background(), client)
 
 	if err != nil {
 		t.Errorf("Expected sunshine, got: %s", err)
@@ -199,7 +413,7 @@ func TestPodSpecInitDelegatesToActionInit(t *testing.T) {
 	action := &recordPodAction{}
 	spec := chaoskube.PodChaosSpec{Action: action}
 
-	err := spec.Init(client)
+	err := spec.Init(context.Background(), client)
 
 	if err != nil {
 		t.Errorf("Expected sunshine, got: %s", err)
@@ -214,11 +428,11 @@ type recordPodAction struct {
 	initCalledWithClient kubernetes.Interface
 }
 
-func (a *recordPodAction) Init(k8sclient kubernetes.Interface) error {
+func (a *recordPodAction) Init(ctx context.Context, k8sclient kubernetes.Interface) error {
 	a.initCalledWithClient = k8sclient
 	return nil
 }
-func (a *recordPodAction) ApplyToPod(victim v1.Pod) error {
+func (a *recordPodAction) ApplyToPod(ctx context.Context, victim v1.Pod) error {
 	a.lastGivenPod = &victim
 	return nil
 }
@@ -226,16 +440,30 @@ func (a *recordPodAction) Name() string {
 	return "record-pod"
 }
 
+// blockingPodAction blocks until its context is done
+type blockingPodAction struct{}
+
+func (a *blockingPodAction) Init(ctx context.Context, k8sclient kubernetes.Interface) error {
+	return nil
+}
+func (a *blockingPodAction) ApplyToPod(ctx context.Context, victim v1.Pod) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+func (a *blockingPodAction) Name() string {
+	return "block pod"
+}
+
 type recordNodeAction struct {
 	lastGivenNode        *v1.Node
 	initCalledWithClient kubernetes.Interface
 }
 
-func (a *recordNodeAction) Init(k8sclient kubernetes.Interface) error {
+func (a *recordNodeAction) Init(ctx context.Context, k8sclient kubernetes.Interface) error {
 	a.initCalledWithClient = k8sclient
 	return nil
 }
-func (a *recordNodeAction) ApplyToNode(client kubernetes.Interface, victim *v1.Node) error {
+func (a *recordNodeAction) ApplyToNode(ctx context.Context, client kubernetes.Interface, victim *v1.Node) error {
 	a.lastGivenNode = victim
 	return nil
 }
@@ -265,6 +493,18 @@ func pod(name string, modifiers ...func(*v1.Pod)) runtime.Object {
 	return &p
 }
 
+func ownedBy(kind, name string) func(pod *v1.Pod) {
+	return func(pod *v1.Pod) {
+		controller := true
+		pod.OwnerReferences = append(pod.OwnerReferences, k8smeta.OwnerReference{
+			APIVersion: "apps/v1",
+			Kind:       kind,
+			Name:       name,
+			Controller: &controller,
+		})
+	}
+}
+
 func label(key, val string) func(pod *v1.Pod) {
 	return func(pod *v1.Pod) {
 		pod.Labels[key] = val
@@ -310,6 +550,64 @@ func selector(spec string) labels.Selector {
 	return selector
 }
 
+// awaitEvent waits for the Event recorder, which works in the background, to create an Event
+func awaitEvent(t *testing.T, client kubernetes.Interface, namespace string) v1.Event {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		events, err := client.CoreV1().Events(namespace).List(k8smeta.ListOptions{})
+		if err != nil {
+			t.Fatalf("Listing Events failed: %s", err)
+		}
+		if len(events.Items) > 0 {
+			return events.Items[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Expected an Event in namespace %s", namespace)
+	return v1.Event{}
+}
+
+// objectRecorder is a fake Event recorder that also remembers what each Event is about, as
+// Kind/Name
+type objectRecorder struct {
+	*record.FakeRecorder
+	objects []string
+}
+
+func newObjectRecorder() *objectRecorder {
+	return &objectRecorder{FakeRecorder: record.NewFakeRecorder(10)}
+}
+
+func (r *objectRecorder) Eventf(object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	r.objects = append(r.objects, objectName(object))
+	r.FakeRecorder.Eventf(object, eventType, reason, messageFmt, args...)
+}
+
+// next returns the object and text of the oldest Event not returned yet
+func (r *objectRecorder) next(t *testing.T) (string, string) {
+	select {
+	case event := <-r.Events:
+		object := r.objects[0]
+		r.objects = r.objects[1:]
+		return object, event
+	default:
+		t.Fatalf("Expected an Event")
+		return "", ""
+	}
+}
+
+func objectName(object runtime.Object) string {
+	switch o := object.(type) {
+	case *v1.ObjectReference:
+		return o.Kind + "/" + o.Name
+	case *v1.Pod:
+		return "Pod/" + o.Name
+	case *v1.Node:
+		return "Node/" + o.Name
+	}
+	return fmt.Sprintf("%T", object)
+}
+
 func asMap(list []string) (out map[string]bool) {
 	out = make(map[string]bool, len(list))
 	for _, name := range list {
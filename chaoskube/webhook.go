@@ -0,0 +1,207 @@
+package chaoskube
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// When webhooks fire
+	WebhookBefore  = "before"
+	WebhookSuccess = "success"
+	WebhookFailure = "failure"
+
+	// SignatureHeader carries the hex encoded HMAC-SHA256 of the body, keyed with the secret
+	SignatureHeader = "X-Marmoset-Signature"
+
+	// DefaultWebhookTemplate is the body sent when no template is configured
+	DefaultWebhookTemplate = `{"event": {{json .Event}}, "time": {{json .Time}}, "experiment": {{json .Experiment}}, ` +
+		`"instance": {{json .Instance}}, "action": {{json .Action}}, "victim": {{json .Victim}}, "error": {{json .Error}}}`
+
+	// how many notifications may wait for delivery before new ones are dropped
+	webhookQueueSize = 100
+)
+
+// WebhookPayload is what the body template of a webhook is rendered with
+type WebhookPayload struct {
+	// one of before, success or failure
+	Event      string
+	Time       time.Time
+	Experiment string
+	Instance   string
+	Action     string
+	Victim     *Victim
+	// why the action failed, if it did
+	Error string
+}
+
+// Webhook is an HTTP endpoint notified about chaos
+type Webhook struct {
+	URL string
+	// the events to notify about; all of them if empty
+	Events []string
+	// renders the JSON body from a WebhookPayload
+	Template *template.Template
+	// if set, requests are signed with it, see SignatureHeader
+	Secret string
+	// how long to wait for a response
+	Timeout time.Duration
+	// how often to retry failed deliveries
+	Retries int
+	// how long to wait before the first retry; it doubles for each one after
+	Backoff time.Duration
+}
+
+// ParseWebhookTemplate parses the body template of a webhook. Besides the usual functions, it
+// provides json, which renders a value as JSON.
+func ParseWebhookTemplate(text string) (*template.Template, error) {
+	return template.New("webhook").Funcs(template.FuncMap{
+		"json": func(value interface{}) (string, error) {
+			encoded, err := json.Marshal(value)
+			return string(encoded), err
+		},
+	}).Parse(text)
+}
+
+// ParseWebhookEvents parses a comma separated list of webhook events
+func ParseWebhookEvents(events string) ([]string, error) {
+	parsedEvents := []string{}
+	for _, event := range strings.Split(events, ",") {
+		event = strings.TrimSpace(event)
+		switch event {
+		case "":
+		case WebhookBefore, WebhookSuccess, WebhookFailure:
+			parsedEvents = append(parsedEvents, event)
+		default:
+			return nil, fmt.Errorf("Invalid webhook event '%v': must be before, success or failure", event)
+		}
+	}
+	return parsedEvents, nil
+}
+
+func (w *Webhook) wants(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver sends the payload, retrying with exponential backoff on network errors and server errors
+func (w *Webhook) deliver(payload WebhookPayload) error {
+	body := &bytes.Buffer{}
+	if err := w.Template.Execute(body, payload); err != nil {
+		return err
+	}
+	if !json.Valid(body.Bytes()) {
+		return fmt.Errorf("template didn't render valid JSON: %s", body)
+	}
+
+	backoff := w.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(body.Bytes())
+		if err == nil || !retry || attempt >= w.Retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post sends one request, and tells whether it's worth trying again if it failed
+func (w *Webhook) post(body []byte) (bool, error) {
+	request, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if w.Secret != "" {
+		request.Header.Set(SignatureHeader, "sha256="+Sign(body, w.Secret))
+	}
+
+	client := &http.Client{Timeout: w.Timeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(ioutil.Discard, response.Body)
+	response.Body.Close()
+
+	switch {
+	case response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("webhook responded %s", response.Status)
+	case response.StatusCode >= 300:
+		return false, fmt.Errorf("webhook responded %s", response.Status)
+	}
+	return false, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body, keyed with secret
+func Sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Webhooks delivers notifications to webhooks in the background, one at a time and in order.
+// Notifying never blocks: if deliveries fall too far behind, new notifications are dropped.
+type Webhooks struct {
+	Hooks []*Webhook
+	// an instance of logrus.StdLogger to write log messages to
+	Logger log.FieldLogger
+
+	queue chan WebhookPayload
+}
+
+// NewWebhooks returns Webhooks delivering to the given hooks
+func NewWebhooks(hooks []*Webhook, logger log.FieldLogger) *Webhooks {
+	w := &Webhooks{
+		Hooks:  hooks,
+		Logger: logger,
+		queue:  make(chan WebhookPayload, webhookQueueSize),
+	}
+	go w.deliverAll()
+	return w
+}
+
+// Notify queues a notification for every webhook interested in the payload's event
+func (w *Webhooks) Notify(payload WebhookPayload) {
+	select {
+	case w.queue <- payload:
+	default:
+		w.Logger.WithField("event", payload.Event).Warn("webhook queue full, dropping notification")
+	}
+}
+
+func (w *Webhooks) deliverAll() {
+	for payload := range w.queue {
+		for _, hook := range w.Hooks {
+			if !hook.wants(payload.Event) {
+				continue
+			}
+			if err := hook.deliver(payload); err != nil {
+				w.Logger.WithFields(log.Fields{
+					"url":   hook.URL,
+					"event": payload.Event,
+					"err":   err,
+				}).Error("failed to notify webhook")
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package chaoskube
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// deliveries log in the background, so they get a logger of their own to not confuse assertLog
+var webhookLogger, _ = test.NewNullLogger()
+
+type webhookRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookServer returns a server answering with the given status codes in turn, and then 200,
+// and a channel receiving the requests it got
+func webhookServer(statusCodes ...int) (*httptest.Server, <-chan webhookRequest) {
+	requests := make(chan webhookRequest, 10)
+	var count int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- webhookRequest{header: r.Header, body: body}
+
+		if i := int(atomic.AddInt32(&count, 1)) - 1; i < len(statusCodes) {
+			w.WriteHeader(statusCodes[i])
+		}
+	}))
+	return server, requests
+}
+
+func (suite *Suite) webhook(url string) *Webhook {
+	template, err := ParseWebhookTemplate(DefaultWebhookTemplate)
+	suite.Require().NoError(err)
+
+	return &Webhook{URL: url, Template: template, Timeout: 1 * time.Second, Retries: 3, Backoff: time.Millisecond}
+}
+
+func (suite *Suite) awaitWebhookRequest(requests <-chan webhookRequest) webhookRequest {
+	select {
+	case request := <-requests:
+		return request
+	case <-time.After(1 * time.Minute):
+		suite.FailNow("expected a webhook request")
+	}
+	return webhookRequest{}
+}
+
+func (suite *Suite) TestWebhookPayload() {
+	server, requests := webhookServer()
+	defer server.Close()
+
+	hook := suite.webhook(server.URL)
+	hook.Secret = "s3cr3t"
+	webhooks := NewWebhooks([]*Webhook{hook}, webhookLogger)
+
+	victim := &Victim{Kind: KindPod, Namespace: "testing", Name: "foo", Action: "terminate pod"}
+	experiment := &Experiment{Name: "kill-foo", Instance: "marmoset-0", Webhooks: webhooks}
+	experiment.notifyApplied(victim, errors.New("boom"))
+
+	request := suite.awaitWebhookRequest(requests)
+	suite.Equal("application/json", request.header.Get("Content-Type"))
+	suite.Equal("sha256="+Sign(request.body, "s3cr3t"), request.header.Get(SignatureHeader))
+
+	payload := map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal(request.body, &payload))
+	suite.Equal(WebhookFailure, payload["event"])
+	suite.Equal("kill-foo", payload["experiment"])
+	suite.Equal("marmoset-0", payload["instance"])
+	suite.Equal("terminate pod", payload["action"])
+	suite.Equal("boom", payload["error"])
+	suite.Equal("foo", payload["victim"].(map[string]interface{})["name"])
+}
+
+func (suite *Suite) TestWebhookRetries() {
+	server, requests := webhookServer(http.StatusServiceUnavailable, http.StatusInternalServerError)
+	defer server.Close()
+
+	webhooks := NewWebhooks([]*Webhook{suite.webhook(server.URL)}, webhookLogger)
+	webhooks.Notify(WebhookPayload{Event: WebhookSuccess})
+
+	for i := 0; i < 3; i++ {
+		suite.awaitWebhookRequest(requests)
+	}
+}
+
+func (suite *Suite) TestWebhookDoesNotRetryClientErrors() {
+	server, _ := webhookServer(http.StatusBadRequest)
+	defer server.Close()
+
+	retry, err := suite.webhook(server.URL).post([]byte("{}"))
+	suite.Error(err)
+	suite.False(retry)
+}
+
+func (suite *Suite) TestWebhookTimeout() {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	hook := suite.webhook(server.URL)
+	hook.Timeout = 10 * time.Millisecond
+	hook.Retries = 0
+
+	suite.Error(hook.deliver(WebhookPayload{Event: WebhookBefore}))
+}
+
+func (suite *Suite) TestWebhookEvents() {
+	server, requests := webhookServer()
+	defer server.Close()
+
+	hook := suite.webhook(server.URL)
+	hook.Events = []string{WebhookFailure}
+	webhooks := NewWebhooks([]*Webhook{hook}, webhookLogger)
+
+	webhooks.Notify(WebhookPayload{Event: WebhookBefore})
+	webhooks.Notify(WebhookPayload{Event: WebhookSuccess})
+	webhooks.Notify(WebhookPayload{Event: WebhookFailure})
+
+	payload := map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal(suite.awaitWebhookRequest(requests).body, &payload))
+	suite.Equal(WebhookFailure, payload["event"])
+}
+
+func (suite *Suite) TestWebhookInvalidTemplate() {
+	template, err := ParseWebhookTemplate(`{"event": {{.Event}}}`)
+	suite.Require().NoError(err)
+
+	hook := &Webhook{URL: "http://localhost", Template: template}
+	suite.Error(hook.deliver(WebhookPayload{Event: WebhookBefore}))
+}
+
+// TestNotifyNeverBlocks tests that a webhook that doesn't answer doesn't hold up chaos
+func (suite *Suite) TestNotifyNeverBlocks() {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	webhooks := NewWebhooks([]*Webhook{suite.webhook(server.URL)}, webhookLogger)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*webhookQueueSize; i++ {
+			webhooks.Notify(WebhookPayload{Event: WebhookBefore})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(1 * time.Minute):
+		suite.FailNow("notifying blocked")
+	}
+}
+
+func (suite *Suite) TestParseWebhookEvents() {
+	events, err := ParseWebhookEvents("before, failure")
+	suite.Require().NoError(err)
+	suite.Equal([]string{WebhookBefore, WebhookFailure}, events)
+
+	events, err = ParseWebhookEvents("")
+	suite.Require().NoError(err)
+	suite.Empty(events)
+
+	_, err = ParseWebhookEvents("after")
+	suite.Error(err)
+}
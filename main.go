@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/neo-technology/marmoset/chaoskube"
 	"github.com/neo-technology/marmoset/chaoskube/action"
 	"github.com/neo-technology/marmoset/util"
+	"io/ioutil"
 	"math/rand"
 	"net/http"
 	"os"
@@ -37,18 +39,83 @@ var (
 	excludedWeekdays   string
 	excludedTimesOfDay string
 	excludedDaysOfYear string
+	allowedWindows     string
+	excludedDates      string
+	calendarFiles      []string
+	calendarConfigMaps []string
 	timezone           string
 	minimumAge         time.Duration
 	master             string
 	kubeconfig         string
 	interval           time.Duration
+	schedule           string
+	intervalMode       string
+	jitter             time.Duration
+	minInterval        time.Duration
+	maxInterval        time.Duration
+	seed               int64
 	actionName         string
 	debug              bool
 	metricsAddress     string
 	exec               string
+	plugins            []string
+	steps              []string
 	execContainer      string
 	logFormat          string
 	logFields          string
+	namespace          string
+	killSwitchMap      string
+	apiToken           string
+	experimentName     string
+	instance           string
+	historyFile        string
+	historyConfigMap   string
+	historySize        int
+	historyFrom        string
+	historyTo          string
+	historyNamespace   string
+	historyAction      string
+	webhookURLs        []string
+	webhookEvents      string
+	webhookTemplate    string
+	webhookSecret      string
+	webhookTimeout     time.Duration
+	webhookRetries     int
+	probes             []string
+	prometheusURL      string
+	probeTimeout       time.Duration
+	pauseOnProbeFail   bool
+	maxNotReadyNodes   int
+	maxCrashLooping    int
+	cordonedNodesGate  bool
+	maxPendingAge      time.Duration
+	limits             []string
+	optIn              bool
+	nsLabelString      string
+	namespaceCacheTTL  time.Duration
+	nodeLabelString    string
+	nodeNames          string
+	ownerKinds         string
+	excludedOwnerKinds string
+	owners             []string
+	excludeBarePods    bool
+	ownerCacheTTL      time.Duration
+	informerCache      bool
+	requireReady       bool
+	maxRestarts        int
+	qosClasses         string
+	priorityClasses    string
+	excludedPriorities string
+	maxPriority        string
+	images             string
+	excludedImages     string
+	victimSelection    string
+	experimentVictims  []string
+	victims            string
+	concurrency        int
+	actionTimeout      time.Duration
+	recoveryTimeout    time.Duration
+	recoverySLO        time.Duration
 )
 
 const (
@@ -60,34 +127,124 @@ const (
 	ACTION_EXEC_POD    = "exec-pod"
 	ACTION_DELETE_NODE = "delete-node"
 	ACTION_DRAIN_NODE  = "drain-node"
+	ACTION_COMPOSITE   = "composite"
+)
+
+const (
+	// Steps of composite actions, besides the actions above
+	STEP_WAIT           = "wait"
+	STEP_UNTIL_REPLACED = "until-replaced"
+	STEP_CORDON_NODE    = "cordon-node"
+	STEP_UNCORDON_NODE  = "uncordon-node"
+)
+
+const (
+	COMMAND_RUN        = "run"
+	COMMAND_CANDIDATES = "candidates"
+	COMMAND_HISTORY    = "history"
+)
+
+const (
+	INTERVAL_FIXED   = "fixed"
+	INTERVAL_JITTER  = "jitter"
+	INTERVAL_POISSON = "poisson"
 )
 
 func init() {
 	rand.Seed(time.Now().UTC().UnixNano())
 
+	kingpin.Command(COMMAND_RUN, "Run chaos on schedule").Default()
+	kingpin.Command(COMMAND_CANDIDATES, "Print what the configured action would currently pick from, and how the filters got there, then exit")
+	history := kingpin.Command(COMMAND_HISTORY, "Print the records of past runs from --history-file or --history-configmap as JSON Lines, then exit")
+	history.Flag("from", "Only runs at or after this time, RFC 3339 or a duration ago like 24h").StringVar(&historyFrom)
+	history.Flag("to", "Only runs before this time, RFC 3339 or a duration ago like 1h").StringVar(&historyTo)
+	history.Flag("victim-namespace", "Only runs with a victim in this namespace").StringVar(&historyNamespace)
+	history.Flag("victim-action", "Only runs of this action, e.g. 'delete pod'").StringVar(&historyAction)
+
 	kingpin.Flag("labels", "A set of labels to restrict the list of affected pods. Defaults to everything.").StringVar(&labelString)
 	kingpin.Flag("annotations", "A set of annotations to restrict the list of affected pods. Defaults to everything.").StringVar(&annString)
 	kingpin.Flag("namespaces", "A set of namespaces to restrict the list of affected pods. Defaults to everything.").StringVar(&nsString)
+	kingpin.Flag("namespace-labels", "A label selector restricting the list of affected pods to those in namespaces with matching labels, e.g. 'team=graph,env=staging'. Defaults to everything.").StringVar(&nsLabelString)
+	kingpin.Flag("namespace-cache-ttl", "How long to keep namespaces looked up for --namespace-labels and --opt-in").Default("1m").DurationVar(&namespaceCacheTTL)
+	kingpin.Flag("node-labels", "A label selector restricting the list of affected pods to those on nodes with matching labels, e.g. 'cloud.google.com/gke-preemptible=true'. Defaults to everything.").StringVar(&nodeLabelString)
+	kingpin.Flag("node-names", "A comma separated list of node names restricting the list of affected pods to those running on one").StringVar(&nodeNames)
+	kingpin.Flag("owner-kinds", "A comma separated list of kinds of owners restricting the list of affected pods to those owned by one, anywhere up the chain of controllers, e.g. StatefulSet,Deployment").StringVar(&ownerKinds)
+	kingpin.Flag("excluded-owner-kinds", "A comma separated list of kinds of owners whose pods are never affected, e.g. Job,CronJob").StringVar(&excludedOwnerKinds)
+	kingpin.Flag("owner", "An owner as <kind>/<name> restricting the list of affected pods to those it owns, e.g. Deployment/frontend; repeatable").StringsVar(&owners)
+	kingpin.Flag("exclude-bare-pods", "Never affect pods without a controller").BoolVar(&excludeBarePods)
+	kingpin.Flag("informer-cache", "Keep pods, nodes and namespaces in memory, watching them for changes, instead of listing them on every run").BoolVar(&informerCache)
+	kingpin.Flag("owner-cache-ttl", "How long to keep the owners of ReplicaSets and Jobs looked up for the owner filters").Default("1m").DurationVar(&ownerCacheTTL)
+	kingpin.Flag("require-ready", "Only affect pods that are Ready").BoolVar(&requireReady)
+	kingpin.Flag("max-restarts", "Only affect pods none of whose containers restarted more often than this, -1 for any number").Default("-1").IntVar(&maxRestarts)
+	kingpin.Flag("qos-classes", "A comma separated list of QoS classes restricting the list of affected pods, e.g. BestEffort,Burstable").StringVar(&qosClasses)
+	kingpin.Flag("priority-classes", "A comma separated list of PriorityClass names restricting the list of affected pods").StringVar(&priorityClasses)
+	kingpin.Flag("excluded-priority-classes", "A comma separated list of PriorityClass names whose pods are never affected, e.g. system-cluster-critical,system-node-critical").StringVar(&excludedPriorities)
+	kingpin.Flag("max-priority", "Only affect pods with at most this priority value").StringVar(&maxPriority)
+	kingpin.Flag("images", "A comma separated list of image patterns, * matching anything, restricting the list of affected pods to those running one, e.g. '*/neo4j:*'").StringVar(&images)
+	kingpin.Flag("excluded-images", "A comma separated list of image patterns, * matching anything, whose pods are never affected").StringVar(&excludedImages)
 	kingpin.Flag("excluded-weekdays", "A list of weekdays when termination is suspended, e.g. Sat,Sun").StringVar(&excludedWeekdays)
 	kingpin.Flag("excluded-times-of-day", "A list of time periods of a day when termination is suspended, e.g. 22:00-08:00").StringVar(&excludedTimesOfDay)
 	kingpin.Flag("excluded-days-of-year", "A list of days of a year when termination is suspended, e.g. Apr1,Dec24").StringVar(&excludedDaysOfYear)
+	kingpin.Flag("excluded-dates", "A list of days or ranges of days when termination is suspended; yearly like Dec20-Jan3 or once like 2026-11-27 and 2026-12-20/2027-01-03").StringVar(&excludedDates)
+	kingpin.Flag("excluded-calendar-file", "Path to an iCalendar (.ics) file of days when termination is suspended. Can be repeated.").StringsVar(&calendarFiles)
+	kingpin.Flag("excluded-calendar-configmap", "A ConfigMap, namespace/name[:key], holding iCalendar (.ics) documents of days when termination is suspended. Can be repeated.").StringsVar(&calendarConfigMaps)
+	kingpin.Flag("allowed-windows", "A list of windows outside of which termination is suspended, e.g. 'Mon-Thu 10:00-16:00,Fri 10:00-12:00'. Exclusions take precedence.").StringVar(&allowedWindows)
 	kingpin.Flag("timezone", "The timezone by which to interpret the excluded weekdays and times of day, e.g. UTC, Local, Europe/Berlin. Defaults to UTC.").Default("UTC").StringVar(&timezone)
 	kingpin.Flag("minimum-age", "Minimum age of pods to consider for termination").Default("0s").DurationVar(&minimumAge)
 	kingpin.Flag("master", "The address of the Kubernetes cluster to target").StringVar(&master)
 	kingpin.Flag("kubeconfig", "Path to a kubeconfig file").StringVar(&kubeconfig)
 	kingpin.Flag("interval", "Interval between Pod terminations").Default("10m").DurationVar(&interval)
+	kingpin.Flag("interval-mode", "How to space runs around --interval: fixed, jitter (uniformly within --jitter of it) or poisson (exponentially distributed gaps with --interval as the mean)").Default(INTERVAL_FIXED).StringVar(&intervalMode)
+	kingpin.Flag("jitter", "Maximum deviation from --interval in 'jitter' interval mode. Defaults to half the interval.").DurationVar(&jitter)
+	kingpin.Flag("min-interval", "Lower bound for randomised intervals").Default("0s").DurationVar(&minInterval)
+	kingpin.Flag("max-interval", "Upper bound for randomised intervals, 0 for none").Default("0s").DurationVar(&maxInterval)
+	kingpin.Flag("victim-selection", "How to pick the victim among the candidates: random, weighted=<annotation or label>, per-owner, least-recently-hit, oldest or newest").Default("random").StringVar(&victimSelection)
+	kingpin.Flag("experiment-victim-selection", "How to pick the victim in one experiment as <experiment>=<strategy>, taking precedence over --victim-selection when --experiment matches. Can be repeated.").StringsVar(&experimentVictims)
+	kingpin.Flag("victims", "How many victims to pick per run: a number, a percentage of the candidates like 30%, or of the replicas each owner wants like 30%/owner").Default("1").StringVar(&victims)
+	kingpin.Flag("recovery-timeout", "How long to wait for the owner of a deleted pod to have as many Ready pods as it wants again, measuring how long that took; 0 not to measure. Only for --action=delete-pod").Default("0s").DurationVar(&recoveryTimeout)
+	kingpin.Flag("recovery-slo", "How long recovery may take before the run is flagged as exceeding it; 0 for no limit").Default("0s").DurationVar(&recoverySLO)
+	kingpin.Flag("action-timeout", "How long an action may take on each victim before it is cancelled, 0 for no limit").Default("0s").DurationVar(&actionTimeout)
+	kingpin.Flag("concurrency", "How many victims of a run to imbue chaos in at a time").Default("1").IntVar(&concurrency)
+	kingpin.Flag("seed", "Seed for all random choices, for deterministic runs. Defaults to the current time.").Int64Var(&seed)
+	kingpin.Flag("schedule", "A cron expression evaluated in --timezone to run chaos by instead of --interval, e.g. '*/20 10-15 * * Mon-Fri'. Can't be combined with --interval-mode, --jitter, --min-interval or --max-interval.").StringVar(&schedule)
 	kingpin.Flag("exec", "Command to use in 'exec' action").StringVar(&exec)
 	kingpin.Flag("exec-container", "Name of container to run --exec command in, defaults to first container in spec").Default("").StringVar(&execContainer)
-	kingpin.Flag("action", "Type of action: dry-run, delete-pod, exec-pod, delete-node, drain-node").Default(ACTION_DRY_RUN).StringVar(&actionName)
+	kingpin.Flag("plugin", "An external binary imbuing chaos in pods or nodes as <name>=<pod|node>:<path>, selected by --action=<name>. Can be repeated.").StringsVar(&plugins)
+	kingpin.Flag("step", "A step of the composite action, done in the order given: an action, cordon-node, uncordon-node, wait=<duration> or until-replaced=<timeout>. Node actions are done to the node of the victim pod, if there is one. Can be repeated.").StringsVar(&steps)
+	kingpin.Flag("action", "Type of action: dry-run, delete-pod, exec-pod, delete-node, drain-node, composite, or the name of a plugin").Default(ACTION_DRY_RUN).StringVar(&actionName)
+	kingpin.Flag("experiment", "Name of the experiment, given in the Events recorded on victims").Default("marmoset").StringVar(&experimentName)
+	kingpin.Flag("instance", "Name of this marmoset instance, given in the Events recorded on victims. Defaults to the hostname.").Envar("POD_NAME").StringVar(&instance)
+	kingpin.Flag("webhook", "URL to POST a JSON notification to before each action and after it succeeds or fails. Can be repeated.").StringsVar(&webhookURLs)
+	kingpin.Flag("webhook-events", "Which of before, success and failure to notify webhooks about, e.g. 'success,failure'. Defaults to all.").StringVar(&webhookEvents)
+	kingpin.Flag("webhook-template-file", "Path to a Go template rendering the JSON body of webhook notifications").StringVar(&webhookTemplate)
+	kingpin.Flag("webhook-secret", "Key to sign webhook notifications with, as an HMAC-SHA256 in the X-Marmoset-Signature header").Envar("MARMOSET_WEBHOOK_SECRET").StringVar(&webhookSecret)
+	kingpin.Flag("webhook-timeout", "How long to wait for a webhook to respond").Default("5s").DurationVar(&webhookTimeout)
+	kingpin.Flag("webhook-retries", "How often to retry a failed webhook notification").Default("3").IntVar(&webhookRetries)
+	kingpin.Flag("probe", "Steady-state probe checked before and after each action: http=<url>[;status=<code>][;body=<text>], prometheus=<query> or pods-ready=<namespace>/<selector>[;within=<duration>]. Can be repeated.").StringsVar(&probes)
+	kingpin.Flag("prometheus-url", "Base URL of the Prometheus server that prometheus probes query").StringVar(&prometheusURL)
+	kingpin.Flag("probe-timeout", "How long to wait for an http or prometheus probe to respond").Default("10s").DurationVar(&probeTimeout)
+	kingpin.Flag("pause-on-probe-failure", "Pause chaos when the steady state isn't restored after an action, until resumed through the API").BoolVar(&pauseOnProbeFail)
+	kingpin.Flag("max-not-ready-nodes", "Suppress chaos while more nodes than this are NotReady. Negative disables the check.").Default("-1").IntVar(&maxNotReadyNodes)
+	kingpin.Flag("max-crashlooping-pods", "Suppress chaos while more pods than this are in CrashLoopBackOff in the target namespaces. Negative disables the check.").Default("-1").IntVar(&maxCrashLooping)
+	kingpin.Flag("suppress-while-cordoned", "Suppress chaos while a node is cordoned by a drain of marmoset").BoolVar(&cordonedNodesGate)
+	kingpin.Flag("max-pending-age", "Suppress chaos while pods are pending for longer than this. 0 disables the check.").Default("0s").DurationVar(&maxPendingAge)
+	kingpin.Flag("limit", "Blast-radius limit as <scope>:<max>/<window>, scope being total, namespace, owner, node or uid, e.g. 'total:3/1h', 'owner:1/30m' or 'uid:1/24h'. Can be repeated. Limits hold across restarts if a history is kept.").StringsVar(&limits)
+	kingpin.Flag("opt-in", "Only target pods annotated, or in a namespace annotated, with marmoset/enabled=true. marmoset/max-per-day and marmoset/actions refine it, marmoset/disabled=true overrides it.").BoolVar(&optIn)
+	kingpin.Flag("history-file", "Path of a file to append a JSON record of every run to").StringVar(&historyFile)
+	kingpin.Flag("history-configmap", "Name of a ConfigMap in --namespace to keep the records of the most recent runs in").StringVar(&historyConfigMap)
+	kingpin.Flag("history-size", "How many records to keep in --history-configmap").Default("500").IntVar(&historySize)
 	kingpin.Flag("debug", "Enable debug logging.").BoolVar(&debug)
 	kingpin.Flag("log-format", "'plain' or 'json'").Default("plain").StringVar(&logFormat)
 	kingpin.Flag("log-fields", "key=value, comma separated list of fields to include in every log message").Default("").StringVar(&logFields)
+	kingpin.Flag("namespace", "The namespace marmoset runs in, holding the kill switch ConfigMap and annotations").Envar("POD_NAMESPACE").StringVar(&namespace)
+	kingpin.Flag("kill-switch-configmap", "Name of the ConfigMap in --namespace whose 'paused: \"true\"' stops all chaos; empty to only honour the namespace annotations").Default("marmoset-kill-switch").StringVar(&killSwitchMap)
 	kingpin.Flag("metrics-address", "Listening address for metrics handler").Default(":8080").StringVar(&metricsAddress)
+	kingpin.Flag("api-token", "Bearer token for the JSON API (/pause, /resume, /trigger, /status) served on --metrics-address; the API is off without one").Envar("MARMOSET_API_TOKEN").StringVar(&apiToken)
 }
 
 func main() {
 	kingpin.Version(version)
-	kingpin.Parse()
+	command := kingpin.Parse()
 
 	logger := chaoskube.SetupLogging(debug, logFormat, logFields)
 
@@ -95,19 +252,79 @@ func main() {
 		"labels":             labelString,
 		"annotations":        annString,
 		"namespaces":         nsString,
+		"namespaceLabels":    nsLabelString,
+		"namespaceCacheTTL":  namespaceCacheTTL,
+		"nodeLabels":         nodeLabelString,
+		"nodeNames":          nodeNames,
+		"ownerKinds":         ownerKinds,
+		"excludedOwnerKinds": excludedOwnerKinds,
+		"owners":             owners,
+		"excludeBarePods":    excludeBarePods,
+		"ownerCacheTTL":      ownerCacheTTL,
+		"informerCache":      informerCache,
+		"requireReady":       requireReady,
+		"maxRestarts":        maxRestarts,
+		"qosClasses":         qosClasses,
+		"priorityClasses":    priorityClasses,
+		"excludedPriorities": excludedPriorities,
+		"maxPriority":        maxPriority,
+		"images":             images,
+		"excludedImages":     excludedImages,
 		"excludedWeekdays":   excludedWeekdays,
 		"excludedTimesOfDay": excludedTimesOfDay,
 		"excludedDaysOfYear": excludedDaysOfYear,
+		"excludedDates":      excludedDates,
+		"calendarFiles":      calendarFiles,
+		"calendarConfigMaps": calendarConfigMaps,
+		"allowedWindows":     allowedWindows,
 		"timezone":           timezone,
 		"minimumAge":         minimumAge,
 		"master":             master,
 		"kubeconfig":         kubeconfig,
 		"interval":           interval,
+		"schedule":           schedule,
+		"intervalMode":       intervalMode,
+		"jitter":             jitter,
+		"minInterval":        minInterval,
+		"maxInterval":        maxInterval,
+		"seed":               seed,
+		"victimSelection":    victimSelection,
+		"experimentVictims":  experimentVictims,
+		"victims":            victims,
+		"concurrency":        concurrency,
+		"actionTimeout":      actionTimeout,
+		"recoveryTimeout":    recoveryTimeout,
+		"recoverySLO":        recoverySLO,
 		"action":             actionName,
 		"exec":               exec,
 		"execContainer":      execContainer,
+		"plugins":            plugins,
+		"steps":              steps,
 		"debug":              debug,
 		"metricsAddress":     metricsAddress,
+		"namespace":          namespace,
+		"killSwitchMap":      killSwitchMap,
+		"api":                apiToken != "",
+		"experiment":         experimentName,
+		"instance":           instance,
+		"historyFile":        historyFile,
+		"historyConfigMap":   historyConfigMap,
+		"historySize":        historySize,
+		"webhooks":           webhookURLs,
+		"webhookEvents":      webhookEvents,
+		"webhookTemplate":    webhookTemplate,
+		"webhookTimeout":     webhookTimeout,
+		"webhookRetries":     webhookRetries,
+		"probes":             probes,
+		"prometheusURL":      prometheusURL,
+		"probeTimeout":       probeTimeout,
+		"pauseOnProbeFail":   pauseOnProbeFail,
+		"maxNotReadyNodes":   maxNotReadyNodes,
+		"maxCrashLooping":    maxCrashLooping,
+		"cordonedNodesGate":  cordonedNodesGate,
+		"maxPendingAge":      maxPendingAge,
+		"limits":             limits,
+		"optIn":              optIn,
 	}).Info("reading config")
 
 	logger.WithFields(log.Fields{
@@ -116,27 +333,50 @@ func main() {
 		"interval": interval,
 	}).Info("starting up")
 
-	config, err := newConfig(logger)
-	if err != nil {
-		logger.WithField("err", err).Fatal("failed to determine k8s client config")
+	if command == COMMAND_HISTORY {
+		// a history file is read without connecting to the cluster
+		var client kubernetes.Interface
+		if historyFile == "" {
+			_, client = connect(logger)
+		}
+		printHistory(parseHistory(client, logger), logger)
+		return
 	}
 
-	client, err := newClient(config, logger)
-	if err != nil {
-		logger.WithField("err", err).Fatal("failed to connect to cluster")
+	if schedule != "" && (intervalMode != INTERVAL_FIXED || jitter != 0 || minInterval > 0 || maxInterval > 0) {
+		// the cron expression would take their place without a word
+		logger.WithFields(log.Fields{
+			"schedule":     schedule,
+			"intervalMode": intervalMode,
+			"jitter":       jitter,
+			"minInterval":  minInterval,
+			"maxInterval":  maxInterval,
+		}).Fatal("--schedule can't be combined with --interval-mode, --jitter, --min-interval or --max-interval")
 	}
 
+	config, client := connect(logger)
+
 	var (
-		labelSelector = parseSelector(labelString, logger)
-		annotations   = parseSelector(annString, logger)
-		namespaces    = parseSelector(nsString, logger)
+		labelSelector   = parseSelector(labelString, logger)
+		annotations     = parseSelector(annString, logger)
+		namespaces      = parseSelector(nsString, logger)
+		namespaceLabels = parseSelector(nsLabelString, logger)
+		nodeLabels      = parseSelector(nodeLabelString, logger)
+		nodeNameList    = parseNodeNames()
+		ownerFilter     = parseOwnerFilter(logger)
+		podFilter       = parsePodFilter(logger)
 	)
 
 	logger.WithFields(log.Fields{
-		"labels":      labelSelector,
-		"annotations": annotations,
-		"namespaces":  namespaces,
-		"minimumAge":  minimumAge,
+		"labels":          labelSelector,
+		"annotations":     annotations,
+		"namespaces":      namespaces,
+		"namespaceLabels": namespaceLabels,
+		"nodeLabels":      nodeLabels,
+		"nodeNames":       nodeNameList,
+		"owners":          ownerFilter,
+		"filter":          podFilter,
+		"minimumAge":      minimumAge,
 	}).Info("setting pod filter")
 
 	parsedWeekdays := util.ParseWeekdays(excludedWeekdays)
@@ -155,12 +395,47 @@ func main() {
 		}).Fatal("failed to parse days of year")
 	}
 
+	parsedDates, err := util.ParseDateRanges(excludedDates)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"dates": excludedDates,
+			"err":   err,
+		}).Fatal("failed to parse dates")
+	}
+
+	calendars := []chaoskube.Calendar{}
+	for _, path := range calendarFiles {
+		calendars = append(calendars, &chaoskube.FileCalendar{Path: path})
+	}
+	for _, ref := range calendarConfigMaps {
+		calendar, err := chaoskube.ParseConfigMapCalendar(ref)
+		if err != nil {
+			logger.WithFields(log.Fields{
+				"calendar": ref,
+				"err":      err,
+			}).Fatal("failed to parse calendar configmap")
+		}
+		calendars = append(calendars, calendar)
+	}
+
+	parsedAllowedWindows, err := util.ParseWindows(allowedWindows)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"allowedWindows": allowedWindows,
+			"err":            err,
+		}).Fatal("failed to parse allowed windows")
+	}
+
 	logger.WithFields(log.Fields{
 		"weekdays":   parsedWeekdays,
 		"timesOfDay": parsedTimesOfDay,
 		"daysOfYear": formatDays(parsedDaysOfYear),
+		"dates":      parsedDates,
+		"calendars":  calendars,
 	}).Info("setting quiet times")
 
+	logger.WithField("allowedWindows", parsedAllowedWindows).Info("setting allowed windows")
+
 	parsedTimezone, err := time.LoadLocation(timezone)
 	if err != nil {
 		logger.WithFields(log.Fields{
@@ -176,44 +451,192 @@ func main() {
 		"offset":   offset / int(time.Hour/time.Second),
 	}).Info("setting timezone")
 
+	if seed != 0 {
+		rand.Seed(seed)
+	}
+	random := rand.New(rand.NewSource(rand.Int63()))
+
+	var runSchedule chaoskube.Schedule
+	switch intervalMode {
+	case INTERVAL_FIXED:
+		runSchedule = chaoskube.Interval(interval)
+	case INTERVAL_JITTER:
+		if jitter < 0 || jitter > interval {
+			logger.WithFields(log.Fields{
+				"jitter":   jitter,
+				"interval": interval,
+			}).Fatal("--jitter must be between 0 and --interval")
+		}
+		if jitter == 0 {
+			jitter = interval / 2
+		}
+		runSchedule = &chaoskube.Jitter{Mean: interval, Jitter: jitter, Rand: random}
+	case INTERVAL_POISSON:
+		runSchedule = &chaoskube.Poisson{Mean: interval, Rand: random}
+	default:
+		panic(fmt.Sprintf("Unknown interval mode: '%s'", intervalMode))
+	}
+	if minInterval > 0 || maxInterval > 0 {
+		runSchedule = &chaoskube.Clamp{Schedule: runSchedule, Min: minInterval, Max: maxInterval}
+	}
+	if schedule != "" {
+		runSchedule, err = util.ParseCron(schedule)
+		if err != nil {
+			logger.WithFields(log.Fields{
+				"schedule": schedule,
+				"err":      err,
+			}).Fatal("failed to parse schedule")
+		}
+	}
+
+	scheduler := chaoskube.NewScheduler(runSchedule, parsedTimezone, logger)
+
+	var killSwitch *chaoskube.KillSwitch
+	if namespace != "" {
+		killSwitch = &chaoskube.KillSwitch{Namespace: namespace, ConfigMap: killSwitchMap}
+		logger.WithFields(log.Fields{
+			"namespace": namespace,
+			"configMap": killSwitchMap,
+		}).Info("setting kill switch")
+	} else {
+		logger.Warn("no namespace given, running without a kill switch")
+	}
+
+	history := parseHistory(client, logger)
+
+	if instance == "" {
+		instance, _ = os.Hostname()
+	}
+	experiment := chaoskube.NewExperiment(experimentName, instance, chaoskube.NewEventRecorder(client, instance, logger.WithField("experiment", experimentName)))
+	if len(webhookURLs) > 0 {
+		experiment.Webhooks = chaoskube.NewWebhooks(parseWebhooks(logger), logger)
+	}
+
+	blastRadius := parseBlastRadius(history, logger)
+	namespaceCache := chaoskube.NewNamespaceCache(namespaceCacheTTL)
+	ownerCache := chaoskube.NewOwnerCache(ownerCacheTTL)
+	var clusterCache *chaoskube.ClusterCache
+	if informerCache && command != COMMAND_CANDIDATES {
+		// listing once is cheaper than watching for a single preview
+		clusterCache = chaoskube.NewClusterCache(client)
+	}
+	selection, err := chaoskube.ParseExperimentVictimSelection(experimentVictims, experimentName, victimSelection)
+	if err != nil {
+		logger.WithField("err", err).Fatal("failed to parse experiment victim selection")
+	}
+	victimSelector, err := chaoskube.ParseVictimSelector(selection, rand.New(rand.NewSource(rand.Int63())))
+	if err != nil {
+		logger.WithField("err", err).Fatal("failed to parse victim selection")
+	}
+	victimCount, err := chaoskube.ParseVictimCount(victims)
+	if err != nil {
+		logger.WithField("err", err).Fatal("failed to parse victims")
+	}
+	recovery := parseRecovery(logger)
+	var podOptIn *chaoskube.OptIn
+	if optIn {
+		podOptIn = &chaoskube.OptIn{Action: actionName}
+	}
+
+	registeredPlugins := parsePlugins(logger)
+
+	// all actions share the filters and limits; they only differ in what they do, and record
+	podSpec := chaoskube.PodChaosSpec{
+		BlastRadius:     blastRadius,
+		VictimSelector:  victimSelector,
+		Count:           victimCount,
+		Concurrency:     concurrency,
+		Cache:           clusterCache,
+		Timeout:         actionTimeout,
+		OptIn:           podOptIn,
+		Labels:          labelSelector,
+		Annotations:     annotations,
+		Namespaces:      namespaces,
+		NamespaceLabels: namespaceLabels,
+		NamespaceCache:  namespaceCache,
+		NodeNames:       nodeNameList,
+		NodeLabels:      nodeLabels,
+		Filter:          podFilter,
+		Owners:          ownerFilter,
+		OwnerCache:      ownerCache,
+		MinimumAge:      minimumAge,
+		Logger:          logger,
+	}
+	nodeSpec := chaoskube.NodeChaosSpec{
+		Experiment:     experiment,
+		BlastRadius:    blastRadius,
+		VictimSelector: victimSelector,
+		Count:          victimCount,
+		Concurrency:    concurrency,
+		Cache:          clusterCache,
+		Timeout:        actionTimeout,
+		Logger:         logger,
+	}
+
 	var spec chaoskube.ChaosSpec
 	switch actionName {
 	case ACTION_DRY_RUN:
-		spec = &chaoskube.PodChaosSpec{
-			Action:      action.NewDryRunPodAction(),
-			Labels:      labelSelector,
-			Annotations: annotations,
-			Namespaces:  namespaces,
-			MinimumAge:  minimumAge,
-			Logger:      logger,
-		}
+		// a dry run records no Events
+		podSpec.Action = action.NewDryRunPodAction()
+		spec = &podSpec
 	case ACTION_DELETE_POD:
-		spec = &chaoskube.PodChaosSpec{
-			Action:      action.NewDeletePodAction(client),
-			Labels:      labelSelector,
-			Annotations: annotations,
-			Namespaces:  namespaces,
-			MinimumAge:  minimumAge,
-			Logger:      logger,
-		}
+		podSpec.Action = action.NewDeletePodAction(client)
+		podSpec.Experiment = experiment
+		podSpec.Recovery = recovery
+		spec = &podSpec
 	case ACTION_EXEC_POD:
-		spec = &chaoskube.PodChaosSpec{
-			Action:      action.NewExecAction(client.CoreV1().RESTClient(), config, execContainer, strings.Split(exec, " ")),
-			Labels:      labelSelector,
-			Annotations: annotations,
-			Namespaces:  namespaces,
-			MinimumAge:  minimumAge,
-			Logger:      logger,
-		}
+		podSpec.Action = action.NewExecAction(client.CoreV1().RESTClient(), config, execContainer, strings.Split(exec, " "))
+		podSpec.Experiment = experiment
+		spec = &podSpec
 	case ACTION_DELETE_NODE:
-		spec = chaoskube.NewNodeChaosSpec(action.NewDeleteNodeAction(), logger)
+		nodeSpec.Action = action.NewDeleteNodeAction()
+		spec = &nodeSpec
 	case ACTION_DRAIN_NODE:
-		spec = chaoskube.NewNodeChaosSpec(action.NewDrainNodeAction(), logger)
+		nodeSpec.Action = action.NewDrainNodeAction()
+		spec = &nodeSpec
+	case ACTION_COMPOSITE:
+		compositeSteps, onPods := parseSteps(client, config, registeredPlugins, logger)
+		if onPods {
+			podSpec.Action = action.NewCompositePodAction(compositeSteps, logger)
+			podSpec.Experiment = experiment
+			spec = &podSpec
+		} else {
+			nodeSpec.Action = action.NewCompositeNodeAction(compositeSteps, logger)
+			spec = &nodeSpec
+		}
 	default:
-		panic(fmt.Sprintf("Unknown action: '%s'", actionName))
+		plugin := registeredPlugins[actionName]
+		switch {
+		case plugin == nil:
+			panic(fmt.Sprintf("Unknown action: '%s'", actionName))
+		case plugin.Kind == action.PluginKindNode:
+			nodeSpec.Action = action.NewPluginNodeAction(*plugin, logger)
+			spec = &nodeSpec
+		default:
+			podSpec.Action = action.NewPluginPodAction(*plugin, logger)
+			podSpec.Experiment = experiment
+			spec = &podSpec
+		}
 	}
 
-	chaoskube := chaoskube.New(
+	if command == COMMAND_CANDIDATES {
+		// the spec isn't initialized, as that may change the cluster, but the limits should show
+		if blastRadius != nil {
+			if err := blastRadius.Init(time.Now()); err != nil {
+				logger.WithField("err", err).Fatal("failed to initialize blast radius")
+			}
+		}
+		report, err := spec.Candidates(client, time.Now())
+		if err != nil {
+			logger.WithField("err", err).Fatal("failed to list candidates")
+		}
+		if err := report.Print(os.Stdout); err != nil {
+			logger.WithField("err", err).Fatal("failed to print candidates")
+		}
+		return
+	}
+
+	monkey := chaoskube.New(
 		client,
 		spec,
 		parsedWeekdays,
@@ -222,6 +645,27 @@ func main() {
 		parsedTimezone,
 		logger,
 	)
+	monkey.ExcludedDateRanges = parsedDates
+	monkey.Calendars = calendars
+	monkey.AllowedWindows = parsedAllowedWindows
+	monkey.KillSwitch = killSwitch
+	if maxNotReadyNodes >= 0 || maxCrashLooping >= 0 || cordonedNodesGate || maxPendingAge > 0 {
+		monkey.HealthGate = &chaoskube.HealthGate{
+			MaxNotReadyNodes:    maxNotReadyNodes,
+			MaxCrashLoopingPods: maxCrashLooping,
+			Namespaces:          namespaces,
+			CordonedNodes:       cordonedNodesGate,
+			MaxPendingAge:       maxPendingAge,
+		}
+	}
+	monkey.History = history
+	monkey.Experiment = experiment
+	if actionName == ACTION_DRY_RUN {
+		// a dry run names its experiment in the history, but records no Events
+		monkey.Experiment = &chaoskube.Experiment{Name: experimentName, Instance: instance}
+	}
+	monkey.Probes = parseProbes(logger)
+	monkey.PauseOnProbeFailure = pauseOnProbeFail
 
 	if metricsAddress != "" {
 		http.Handle("/metrics", promhttp.Handler())
@@ -229,6 +673,9 @@ func main() {
 			func(w http.ResponseWriter, r *http.Request) {
 				fmt.Fprintln(w, "OK")
 			})
+		if apiToken != "" {
+			chaoskube.NewAPI(monkey, scheduler, apiToken, logger).Register(http.DefaultServeMux)
+		}
 		http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 			w.Write([]byte(`<html>
 					<head><title>chaoskube</title></head>
@@ -259,10 +706,21 @@ func main() {
 		cancel()
 	}()
 
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	monkey.Run(ctx, scheduler.Ticks(ctx))
+}
+
+// connect returns the config of the cluster and a client for it
+func connect(logger log.FieldLogger) (*restclient.Config, *kubernetes.Clientset) {
+	config, err := newConfig(logger)
+	if err != nil {
+		logger.WithField("err", err).Fatal("failed to determine k8s client config")
+	}
 
-	chaoskube.Run(ctx, ticker.C)
+	client, err := newClient(config, logger)
+	if err != nil {
+		logger.WithField("err", err).Fatal("failed to connect to cluster")
+	}
+	return config, client
 }
 
 func newConfig(logger log.FieldLogger) (*restclient.Config, error) {
@@ -315,6 +773,230 @@ func parseSelector(str string, logger log.FieldLogger) labels.Selector {
 	return selector
 }
 
+func parseWebhooks(logger log.FieldLogger) []*chaoskube.Webhook {
+	events, err := chaoskube.ParseWebhookEvents(webhookEvents)
+	if err != nil {
+		logger.WithField("err", err).Fatal("failed to parse webhook events")
+	}
+
+	templateText := chaoskube.DefaultWebhookTemplate
+	if webhookTemplate != "" {
+		contents, err := ioutil.ReadFile(webhookTemplate)
+		if err != nil {
+			logger.WithField("err", err).Fatal("failed to read webhook template")
+		}
+		templateText = string(contents)
+	}
+	template, err := chaoskube.ParseWebhookTemplate(templateText)
+	if err != nil {
+		logger.WithField("err", err).Fatal("failed to parse webhook template")
+	}
+
+	webhooks := make([]*chaoskube.Webhook, 0, len(webhookURLs))
+	for _, url := range webhookURLs {
+		webhooks = append(webhooks, &chaoskube.Webhook{
+			URL:      url,
+			Events:   events,
+			Template: template,
+			Secret:   webhookSecret,
+			Timeout:  webhookTimeout,
+			Retries:  webhookRetries,
+			Backoff:  1 * time.Second,
+		})
+	}
+	return webhooks
+}
+
+// parsePlugins returns the plugins by name
+func parsePlugins(logger log.FieldLogger) map[string]*action.Plugin {
+	parsed := map[string]*action.Plugin{}
+	for _, definition := range plugins {
+		plugin, err := action.ParsePlugin(definition)
+		if err != nil {
+			logger.WithField("err", err).Fatal("failed to parse plugin")
+		}
+		switch plugin.Name {
+		case ACTION_DRY_RUN, ACTION_DELETE_POD, ACTION_EXEC_POD, ACTION_DELETE_NODE, ACTION_DRAIN_NODE, ACTION_COMPOSITE,
+			STEP_WAIT, STEP_UNTIL_REPLACED, STEP_CORDON_NODE, STEP_UNCORDON_NODE:
+			logger.WithField("plugin", plugin.Name).Fatal("plugin named like a built-in action")
+		}
+		if parsed[plugin.Name] != nil {
+			logger.WithField("plugin", plugin.Name).Fatal("plugin registered twice")
+		}
+		parsed[plugin.Name] = &plugin
+	}
+	return parsed
+}
+
+// parseSteps returns the steps of the composite action, and whether they are done to pods rather
+// than nodes
+func parseSteps(client kubernetes.Interface, config *restclient.Config, plugins map[string]*action.Plugin, logger log.FieldLogger) ([]action.Step, bool) {
+	parsed := []action.Step{}
+	onPods := false
+	for _, step := range steps {
+		parts := strings.SplitN(step, "=", 2)
+		if parts[0] == STEP_WAIT || parts[0] == STEP_UNTIL_REPLACED {
+			if len(parts) != 2 {
+				logger.WithField("step", step).Fatal("step needs a duration")
+			}
+			duration, err := time.ParseDuration(parts[1])
+			if err != nil {
+				logger.WithFields(log.Fields{"step": step, "err": err}).Fatal("failed to parse step")
+			}
+			if parts[0] == STEP_WAIT {
+				parsed = append(parsed, action.NewWaitStep(duration))
+			} else {
+				parsed = append(parsed, action.NewUntilStep("replaced", action.PodReplaced, duration, logger))
+				onPods = true
+			}
+			continue
+		}
+
+		switch step {
+		case ACTION_DRY_RUN:
+			parsed = append(parsed, action.NewPodStep(action.NewDryRunPodAction()))
+			onPods = true
+		case ACTION_DELETE_POD:
+			parsed = append(parsed, action.NewPodStep(action.NewDeletePodAction(client)))
+			onPods = true
+		case ACTION_EXEC_POD:
+			parsed = append(parsed, action.NewPodStep(action.NewExecAction(client.CoreV1().RESTClient(), config, execContainer, strings.Split(exec, " "))))
+			onPods = true
+		case ACTION_DELETE_NODE:
+			parsed = append(parsed, action.NewNodeStep(action.NewDeleteNodeAction()))
+		case ACTION_DRAIN_NODE:
+			parsed = append(parsed, action.NewNodeStep(action.NewDrainNodeAction()))
+		case STEP_CORDON_NODE:
+			parsed = append(parsed, action.NewNodeStep(action.NewCordonNodeAction()))
+		case STEP_UNCORDON_NODE:
+			parsed = append(parsed, action.NewNodeStep(action.NewUncordonNodeAction()))
+		default:
+			plugin := plugins[step]
+			switch {
+			case plugin == nil:
+				logger.WithField("step", step).Fatal("unknown step")
+			case plugin.Kind == action.PluginKindNode:
+				parsed = append(parsed, action.NewNodeStep(action.NewPluginNodeAction(*plugin, logger)))
+			default:
+				parsed = append(parsed, action.NewPodStep(action.NewPluginPodAction(*plugin, logger)))
+				onPods = true
+			}
+		}
+	}
+
+	if len(parsed) == 0 {
+		logger.Fatal("composite action without steps")
+	}
+	return parsed, onPods
+}
+
+// parseRecovery returns how to measure recovery, or nil if it isn't. Only deleting a pod is sure
+// to take a Ready pod from its owner; after other actions the owner would seem to recover at once.
+func parseRecovery(logger log.FieldLogger) *chaoskube.Recovery {
+	if recoveryTimeout == 0 {
+		if recoverySLO > 0 {
+			logger.Fatal("--recovery-slo needs --recovery-timeout")
+		}
+		return nil
+	}
+	if actionName != ACTION_DELETE_POD {
+		logger.WithField("action", actionName).Fatal("--recovery-timeout only applies to --action=" + ACTION_DELETE_POD)
+	}
+	return &chaoskube.Recovery{Timeout: recoveryTimeout, SLO: recoverySLO}
+}
+
+func parseNodeNames() []string {
+	names := []string{}
+	for _, name := range strings.Split(nodeNames, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func parsePodFilter(logger log.FieldLogger) *chaoskube.PodFilter {
+	filter, err := chaoskube.ParsePodFilter(requireReady, maxRestarts, qosClasses, priorityClasses, excludedPriorities, maxPriority, images, excludedImages)
+	if err != nil {
+		logger.WithField("err", err).Fatal("failed to parse pod filter")
+	}
+	return filter
+}
+
+func parseOwnerFilter(logger log.FieldLogger) *chaoskube.OwnerFilter {
+	filter, err := chaoskube.ParseOwnerFilter(ownerKinds, excludedOwnerKinds, owners, excludeBarePods)
+	if err != nil {
+		logger.WithField("err", err).Fatal("failed to parse owner filter")
+	}
+	return filter
+}
+
+func parseBlastRadius(history chaoskube.History, logger log.FieldLogger) *chaoskube.BlastRadius {
+	if len(limits) == 0 && !optIn {
+		return nil
+	}
+
+	parsedLimits := make([]chaoskube.Limit, 0, len(limits))
+	for _, text := range limits {
+		limit, err := chaoskube.ParseLimit(text)
+		if err != nil {
+			logger.WithField("err", err).Fatal("failed to parse limit")
+		}
+		parsedLimits = append(parsedLimits, limit)
+	}
+	// the opt-in mode keeps at least a day of victims, see PodChaosSpec.Init
+	return &chaoskube.BlastRadius{Limits: parsedLimits, History: history}
+}
+
+func parseProbes(logger log.FieldLogger) []chaoskube.Probe {
+	parsedProbes := make([]chaoskube.Probe, 0, len(probes))
+	for _, text := range probes {
+		probe, err := chaoskube.ParseProbe(text, prometheusURL, probeTimeout)
+		if err != nil {
+			logger.WithField("err", err).Fatal("failed to parse probe")
+		}
+		parsedProbes = append(parsedProbes, probe)
+	}
+	return parsedProbes
+}
+
+// parseHistory returns the history to keep, or nil if none is kept
+func parseHistory(client kubernetes.Interface, logger log.FieldLogger) chaoskube.History {
+	switch {
+	case historyFile != "" && historyConfigMap != "":
+		logger.Fatal("--history-file and --history-configmap are mutually exclusive")
+	case historyFile != "":
+		return &chaoskube.FileHistory{Path: historyFile}
+	case historyConfigMap != "":
+		if namespace == "" {
+			logger.Fatal("--history-configmap needs --namespace")
+		}
+		return &chaoskube.ConfigMapHistory{Client: client, Namespace: namespace, Name: historyConfigMap, Size: historySize}
+	}
+	return nil
+}
+
+func printHistory(history chaoskube.History, logger log.FieldLogger) {
+	if history == nil {
+		logger.Fatal("no history is kept, set --history-file or --history-configmap")
+	}
+
+	query, err := chaoskube.ParseHistoryQuery(historyFrom, historyTo, historyNamespace, historyAction, time.Now())
+	if err != nil {
+		logger.WithField("err", err).Fatal("failed to parse history query")
+	}
+
+	records, err := history.Query(query)
+	if err != nil {
+		logger.WithField("err", err).Fatal("failed to read history")
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	for _, record := range records {
+		encoder.Encode(record)
+	}
+}
+
 func formatDays(days []time.Time) []string {
 	formattedDays := make([]string, 0, len(days))
 	for _, d := range days {
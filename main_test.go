@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func TestParseFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{COMMAND_RUN, "--action", "delete-pod", "--experiment-victim-selection", "kill-a=oldest"},
+		{COMMAND_CANDIDATES, "--namespaces", "default"},
+		{COMMAND_HISTORY, "--from", "24h", "--victim-namespace", "default", "--victim-action", "delete pod"},
+	} {
+		if _, err := kingpin.CommandLine.Parse(args); err != nil {
+			t.Errorf("Failed to parse %v: %s", args, err)
+		}
+	}
+}
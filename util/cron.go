@@ -0,0 +1,214 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed standard five-field cron expression: minute, hour, day of month,
+// month and day of week. It is evaluated in the location of the time passed to Next.
+type CronSchedule struct {
+	expr string
+
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// whether day of month/week were given as '*', which changes how they combine
+	domStar bool
+	dowStar bool
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute = cronField{0, 59, nil}
+	cronHour   = cronField{0, 23, nil}
+	cronDom    = cronField{1, 31, nil}
+	cronMonth  = cronField{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is accepted as an alias for Sunday
+	cronDow = cronField{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+
+	cronDescriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// ParseCron parses a standard cron expression such as "*/20 10-15 * * Mon-Fri". Lists, ranges,
+// steps, month and weekday names and the usual @daily style descriptors are supported.
+func ParseCron(expr string) (*CronSchedule, error) {
+	spec := strings.TrimSpace(expr)
+	if descriptor, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = descriptor
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Invalid cron expression '%v': must contain exactly five fields", expr)
+	}
+
+	schedule := &CronSchedule{expr: expr}
+	var err error
+	if schedule.minute, err = parseCronField(fields[0], cronMinute); err != nil {
+		return nil, err
+	}
+	if schedule.hour, err = parseCronField(fields[1], cronHour); err != nil {
+		return nil, err
+	}
+	if schedule.dom, err = parseCronField(fields[2], cronDom); err != nil {
+		return nil, err
+	}
+	if schedule.month, err = parseCronField(fields[3], cronMonth); err != nil {
+		return nil, err
+	}
+	if schedule.dow, err = parseCronField(fields[4], cronDow); err != nil {
+		return nil, err
+	}
+	// fold Sunday-as-7 onto Sunday-as-0
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+	schedule.domStar = strings.HasPrefix(fields[2], "*")
+	schedule.dowStar = strings.HasPrefix(fields[4], "*")
+
+	return schedule, nil
+}
+
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			rangePart = item[:i]
+			step, err = strconv.Atoi(item[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("Invalid step in cron field '%v'", field)
+			}
+		}
+
+		var from, to int
+		switch {
+		case rangePart == "*":
+			from, to = bounds.min, bounds.max
+		case strings.Contains(rangePart, "-"):
+			parts := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if from, err = parseCronValue(parts[0], bounds); err != nil {
+				return 0, err
+			}
+			if to, err = parseCronValue(parts[1], bounds); err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			if from, err = parseCronValue(rangePart, bounds); err != nil {
+				return 0, err
+			}
+			to = from
+			// a single value with a step, like 5/15, runs to the end of the range
+			if step > 1 {
+				to = bounds.max
+			}
+		}
+
+		if from > to {
+			return 0, fmt.Errorf("Invalid range '%v' in cron field '%v'", rangePart, field)
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(value string, bounds cronField) (int, error) {
+	if n, ok := bounds.names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid cron value '%v'", value)
+	}
+	if n < bounds.min || n > bounds.max {
+		return 0, fmt.Errorf("Cron value %d out of range [%d, %d]", n, bounds.min, bounds.max)
+	}
+	return n, nil
+}
+
+// Next returns the first point in time strictly after t that matches the schedule, evaluated in
+// t's location. It returns the zero time if there is no such point within the next five years.
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	yearLimit := t.Year() + 5
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for c.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !c.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for c.hour&(1<<uint(t.Hour())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for c.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	return t
+}
+
+// dayMatches follows the traditional cron rule: if both day of month and day of week are
+// restricted, a day matching either is enough.
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// String returns the expression the schedule was parsed from.
+func (c *CronSchedule) String() string {
+	return c.expr
+}
@@ -0,0 +1,101 @@
+package util
+
+import (
+	"time"
+)
+
+func (suite *Suite) TestCronNext() {
+	london, err := time.LoadLocation("Europe/London")
+	suite.Require().NoError(err)
+
+	for _, tt := range []struct {
+		expr     string
+		after    time.Time
+		expected time.Time
+	}{
+		// every minute
+		{
+			"* * * * *",
+			time.Date(2026, 11, 27, 10, 15, 30, 0, time.UTC),
+			time.Date(2026, 11, 27, 10, 16, 0, 0, time.UTC),
+		},
+		// strictly after the given time
+		{
+			"30 10 * * *",
+			time.Date(2026, 11, 27, 10, 30, 0, 0, time.UTC),
+			time.Date(2026, 11, 28, 10, 30, 0, 0, time.UTC),
+		},
+		// every 20 minutes from 10:00 to 16:00 on weekdays, within the window
+		{
+			"*/20 10-15 * * Mon-Fri",
+			time.Date(2026, 11, 27, 10, 5, 0, 0, time.UTC), // a Friday
+			time.Date(2026, 11, 27, 10, 20, 0, 0, time.UTC),
+		},
+		// ... and rolling over the weekend
+		{
+			"*/20 10-15 * * Mon-Fri",
+			time.Date(2026, 11, 27, 15, 45, 0, 0, time.UTC),
+			time.Date(2026, 11, 30, 10, 0, 0, 0, time.UTC),
+		},
+		// evaluated in the location of the given time
+		{
+			"0 9 * * *",
+			time.Date(2026, 7, 1, 8, 30, 0, 0, london),
+			time.Date(2026, 7, 1, 9, 0, 0, 0, london),
+		},
+		// lists and month names
+		{
+			"0 0 1 jan,jul *",
+			time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+		},
+		// day of month and day of week combine with OR when both are restricted
+		{
+			"0 12 13 * fri",
+			time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 11, 6, 12, 0, 0, 0, time.UTC),
+		},
+		// 7 means Sunday, too
+		{
+			"0 0 * * 7",
+			time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 11, 29, 0, 0, 0, 0, time.UTC),
+		},
+		// descriptors
+		{
+			"@daily",
+			time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC),
+			time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		// a day that never comes
+		{
+			"0 0 30 2 *",
+			time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Time{},
+		},
+	} {
+		schedule, err := ParseCron(tt.expr)
+		suite.Require().NoError(err)
+
+		suite.Equal(tt.expected, schedule.Next(tt.after), tt.expr)
+	}
+}
+
+func (suite *Suite) TestParseCronErrors() {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"foo * * * *",
+	} {
+		_, err := ParseCron(expr)
+		suite.Error(err, expr)
+	}
+}
@@ -0,0 +1,146 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// the iCalendar DATE value format.
+	icalDate = "20060102"
+)
+
+// ParseICalendar reads the events of an iCalendar (.ics) document and turns them into a slice of
+// DateRanges covering every day an event touches. Events recurring with FREQ=YEARLY become yearly
+// ranges; any other recurrence rule is ignored and only the first occurrence is used.
+func ParseICalendar(r io.Reader) ([]DateRange, error) {
+	lines, err := unfoldICalendar(r)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedRanges := []DateRange{}
+
+	var event map[string]icalProperty
+	for i, line := range lines {
+		name, prop, err := parseICalendarLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid iCalendar line %d: %s", i+1, err)
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT"):
+			event = map[string]icalProperty{}
+		case name == "END" && strings.EqualFold(prop.value, "VEVENT"):
+			if event == nil {
+				return nil, fmt.Errorf("Invalid iCalendar line %d: END:VEVENT without BEGIN", i+1)
+			}
+			dateRange, err := icalEventRange(event)
+			if err != nil {
+				return nil, err
+			}
+			parsedRanges = append(parsedRanges, dateRange)
+			event = nil
+		case event != nil:
+			event[name] = prop
+		}
+	}
+
+	return parsedRanges, nil
+}
+
+type icalProperty struct {
+	params string
+	value  string
+}
+
+// unfoldICalendar splits an iCalendar document into its logical lines, joining continuation
+// lines that start with a space or tab.
+func unfoldICalendar(r io.Reader) ([]string, error) {
+	lines := []string{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+// parseICalendarLine splits a content line like DTSTART;VALUE=DATE:20261127 into its upper-cased
+// name, its parameters and its value.
+func parseICalendarLine(line string) (string, icalProperty, error) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return "", icalProperty{}, fmt.Errorf("missing ':' in '%v'", line)
+	}
+
+	name, params := line[:colon], ""
+	if semicolon := strings.Index(name, ";"); semicolon >= 0 {
+		name, params = name[:semicolon], name[semicolon+1:]
+	}
+
+	return strings.ToUpper(name), icalProperty{params: params, value: line[colon+1:]}, nil
+}
+
+func icalEventRange(event map[string]icalProperty) (DateRange, error) {
+	start, ok := event["DTSTART"]
+	if !ok {
+		return DateRange{}, fmt.Errorf("Invalid iCalendar event '%v': missing DTSTART", event["SUMMARY"].value)
+	}
+
+	from, _, err := parseICalendarTime(start.value)
+	if err != nil {
+		return DateRange{}, err
+	}
+
+	to := from
+	if end, ok := event["DTEND"]; ok {
+		var endsAtMidnight bool
+		if to, endsAtMidnight, err = parseICalendarTime(end.value); err != nil {
+			return DateRange{}, err
+		}
+		// ends are exclusive, so an event ending at midnight doesn't touch that day
+		if endsAtMidnight && to.After(from) {
+			to = to.AddDate(0, 0, -1)
+		}
+	}
+
+	yearly := strings.Contains(strings.ToUpper(event["RRULE"].value), "FREQ=YEARLY")
+
+	dateRange := NewDateRange(from, to, yearly)
+	dateRange.Name = unescapeICalendarText(event["SUMMARY"].value)
+	return dateRange, nil
+}
+
+// parseICalendarTime parses a DATE or DATE-TIME value down to its day, and reports whether it
+// refers to the very beginning of that day.
+func parseICalendarTime(value string) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < len(icalDate) {
+		return time.Time{}, false, fmt.Errorf("Invalid iCalendar date '%v'", value)
+	}
+
+	day, err := time.Parse(icalDate, value[:len(icalDate)])
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("Invalid iCalendar date '%v': %s", value, err)
+	}
+
+	timeOfDay := strings.TrimSuffix(value[len(icalDate):], "Z")
+	return day, timeOfDay == "" || timeOfDay == "T000000", nil
+}
+
+func unescapeICalendarText(text string) string {
+	return strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`).Replace(text)
+}
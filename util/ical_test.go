@@ -0,0 +1,68 @@
+package util
+
+import (
+	"strings"
+	"time"
+)
+
+func (suite *Suite) TestParseICalendar() {
+	calendar := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Example//Holidays//EN",
+		"BEGIN:VEVENT",
+		"UID:thanksgiving@example.com",
+		"DTSTART;VALUE=DATE:20261126",
+		"DTEND;VALUE=DATE:20261128",
+		"SUMMARY:Thanksgiving\\, and the day after",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:christmas@example.com",
+		"DTSTART;VALUE=DATE:20261225",
+		"RRULE:FREQ=YEARLY",
+		"SUMMARY:Christmas",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:freeze@example.com",
+		"DTSTART:20261214T090000Z",
+		"DTEND:20261216T170000Z",
+		"SUMMARY:Change freeze for the",
+		"  year-end release",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	ranges, err := ParseICalendar(strings.NewReader(calendar))
+	suite.Require().NoError(err)
+
+	suite.Equal([]DateRange{
+		{
+			From: time.Date(2026, 11, 26, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC),
+			Name: "Thanksgiving, and the day after",
+		},
+		{
+			From:   time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC),
+			To:     time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC),
+			Yearly: true,
+			Name:   "Christmas",
+		},
+		{
+			From: time.Date(2026, 12, 14, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2026, 12, 16, 0, 0, 0, 0, time.UTC),
+			Name: "Change freeze for the year-end release",
+		},
+	}, ranges)
+}
+
+func (suite *Suite) TestParseICalendarErrors() {
+	for _, calendar := range []string{
+		"BEGIN:VEVENT\nSUMMARY:no start\nEND:VEVENT",
+		"BEGIN:VEVENT\nDTSTART:2026\nEND:VEVENT",
+		"BEGIN:VEVENT\nthis is not a property\nEND:VEVENT",
+		"END:VEVENT",
+	} {
+		_, err := ParseICalendar(strings.NewReader(calendar))
+		suite.Error(err, calendar)
+	}
+}
@@ -14,6 +14,8 @@ const (
 	Kitchen24 = "15:04"
 	// a time format that just cares about the day and month.
 	YearDay = "Jan_2"
+	// a time format for a specific day, as in ISO 8601.
+	Date = "2006-01-02"
 )
 
 // TimePeriod represents a time period with a single beginning and end.
@@ -52,22 +54,61 @@ func (tp TimePeriod) MarshalJSON() ([]byte, error) {
 	return []byte(formatted), nil
 }
 
+// Window represents a time period of the day that only applies on certain weekdays.
+type Window struct {
+	Weekdays []time.Weekday
+	Period   TimePeriod
+}
+
+// Includes returns true iff the given pointInTime falls into window w. A period that wraps around
+// midnight belongs to the weekday it starts on, e.g. "Fri 22:00-02:00" includes early Saturday.
+func (w Window) Includes(pointInTime time.Time) bool {
+	if !w.Period.Includes(pointInTime) {
+		return false
+	}
+
+	weekday := pointInTime.Weekday()
+	if w.Period.From.After(w.Period.To) && TimeOfDay(pointInTime).Before(w.Period.To) {
+		weekday = (weekday + 6) % 7
+	}
+
+	for _, wd := range w.Weekdays {
+		if wd == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns w as a pretty string.
+func (w Window) String() string {
+	days := make([]string, 0, len(w.Weekdays))
+	for _, wd := range w.Weekdays {
+		days = append(days, wd.String()[:3])
+	}
+	return fmt.Sprintf("%s %s", strings.Join(days, "+"), w.Period)
+}
+
+func (w Window) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%q", w.String())), nil
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
 // ParseWeekdays takes a comma-separated list of abbreviated weekdays (e.g. sat,sun) and turns them
 // into a slice of time.Weekday. It ignores any whitespace and any invalid weekdays.
 func ParseWeekdays(weekdays string) []time.Weekday {
-	var days = map[string]time.Weekday{
-		"sun": time.Sunday,
-		"mon": time.Monday,
-		"tue": time.Tuesday,
-		"wed": time.Wednesday,
-		"thu": time.Thursday,
-		"fri": time.Friday,
-		"sat": time.Saturday,
-	}
-
 	parsedWeekdays := []time.Weekday{}
 	for _, wd := range strings.Split(weekdays, ",") {
-		if day, ok := days[strings.TrimSpace(strings.ToLower(wd))]; ok {
+		if day, ok := weekdayNames[strings.TrimSpace(strings.ToLower(wd))]; ok {
 			parsedWeekdays = append(parsedWeekdays, day)
 		}
 	}
@@ -105,6 +146,162 @@ func ParseTimePeriods(timePeriods string) ([]TimePeriod, error) {
 	return parsedTimePeriods, nil
 }
 
+// DateRange represents an inclusive range of days. A yearly range repeats every year and ignores
+// the years of From and To; it may wrap around the turn of the year, e.g. Dec20-Jan3.
+type DateRange struct {
+	From   time.Time
+	To     time.Time
+	Yearly bool
+	// an optional description, e.g. the summary of a calendar entry
+	Name string
+}
+
+// NewDateRange returns a DateRange covering the days from and to, ignoring their times of day.
+func NewDateRange(from, to time.Time, yearly bool) DateRange {
+	return DateRange{From: Day(from), To: Day(to), Yearly: yearly}
+}
+
+// Includes returns true iff the given pointInTime's day lies within date range r.
+func (r DateRange) Includes(pointInTime time.Time) bool {
+	day := Day(pointInTime)
+
+	if !r.Yearly {
+		return !day.Before(r.From) && !day.After(r.To)
+	}
+
+	from := time.Date(day.Year(), r.From.Month(), r.From.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(day.Year(), r.To.Month(), r.To.Day(), 0, 0, 0, 0, time.UTC)
+	if !from.After(to) {
+		return !day.Before(from) && !day.After(to)
+	}
+	return !day.Before(from) || !day.After(to)
+}
+
+// String returns r as a pretty string.
+func (r DateRange) String() string {
+	layout := Date
+	if r.Yearly {
+		layout = YearDay
+	}
+
+	formatted := r.From.Format(layout)
+	if !r.To.Equal(r.From) {
+		formatted = fmt.Sprintf("%s..%s", formatted, r.To.Format(layout))
+	}
+	if r.Name != "" {
+		formatted = fmt.Sprintf("%s (%s)", formatted, r.Name)
+	}
+	return formatted
+}
+
+func (r DateRange) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%q", r.String())), nil
+}
+
+// ParseDateRanges takes a comma-separated list of days or ranges of days and turns them into a
+// slice of DateRanges. Days in YearDay format (e.g. Dec24) and ranges of them (e.g. Dec20-Jan3)
+// repeat every year. Days in Date format (e.g. 2026-11-27) and ranges of them written as ISO 8601
+// intervals (e.g. 2026-12-20/2027-01-03) only apply once. It ignores any whitespace.
+func ParseDateRanges(ranges string) ([]DateRange, error) {
+	parsedRanges := []DateRange{}
+
+	for _, r := range strings.Split(ranges, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+
+		separator, layout, yearly := "/", Date, false
+		if _, err := time.Parse(Date, strings.TrimSpace(strings.SplitN(r, "/", 2)[0])); err != nil {
+			separator, layout, yearly = "-", YearDay, true
+		}
+
+		parts := strings.Split(r, separator)
+		if len(parts) > 2 {
+			return nil, fmt.Errorf("Invalid date range '%v': must contain at most one '%s'", r, separator)
+		}
+
+		from, err := time.Parse(layout, strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, err
+		}
+
+		to, err := time.Parse(layout, strings.TrimSpace(parts[len(parts)-1]))
+		if err != nil {
+			return nil, err
+		}
+
+		if !yearly && to.Before(from) {
+			return nil, fmt.Errorf("Invalid date range '%v': ends before it begins", r)
+		}
+
+		parsedRanges = append(parsedRanges, NewDateRange(from, to, yearly))
+	}
+
+	return parsedRanges, nil
+}
+
+// ParseWindows takes a comma-separated list of windows and turns them into a slice of Windows.
+// Each window is a weekday or a range of weekdays followed by a time period in Kitchen24 format,
+// e.g. "Mon-Thu 10:00-16:00, Fri 10:00-12:00". Without weekdays, a window applies every day.
+func ParseWindows(windows string) ([]Window, error) {
+	parsedWindows := []Window{}
+
+	for _, w := range strings.Split(windows, ",") {
+		fields := strings.Fields(w)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("Invalid window '%v': must be weekdays followed by a time period", w)
+		}
+
+		weekdays := []time.Weekday{
+			time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday,
+		}
+		if len(fields) == 2 {
+			var err error
+			if weekdays, err = parseWeekdayRange(fields[0]); err != nil {
+				return nil, err
+			}
+		}
+
+		periods, err := ParseTimePeriods(fields[len(fields)-1])
+		if err != nil {
+			return nil, err
+		}
+
+		parsedWindows = append(parsedWindows, Window{Weekdays: weekdays, Period: periods[0]})
+	}
+
+	return parsedWindows, nil
+}
+
+// parseWeekdayRange turns a weekday (e.g. fri) or an inclusive range of weekdays (e.g. mon-thu)
+// into a slice of time.Weekday. Ranges may wrap around the end of the week, e.g. fri-mon.
+func parseWeekdayRange(weekdays string) ([]time.Weekday, error) {
+	parts := strings.Split(strings.ToLower(weekdays), "-")
+	if len(parts) > 2 {
+		return nil, fmt.Errorf("Invalid weekday range '%v': must contain at most one '-'", weekdays)
+	}
+
+	from, ok := weekdayNames[parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("Invalid weekday '%v'", parts[0])
+	}
+	to, ok := weekdayNames[parts[len(parts)-1]]
+	if !ok {
+		return nil, fmt.Errorf("Invalid weekday '%v'", parts[len(parts)-1])
+	}
+
+	parsedWeekdays := []time.Weekday{from}
+	for wd := from; wd != to; {
+		wd = (wd + 1) % 7
+		parsedWeekdays = append(parsedWeekdays, wd)
+	}
+	return parsedWeekdays, nil
+}
+
 func ParseDays(days string) ([]time.Time, error) {
 	parsedDays := []time.Time{}
 
@@ -130,6 +327,12 @@ func TimeOfDay(pointInTime time.Time) time.Time {
 	return time.Date(0, 0, 0, pointInTime.Hour(), pointInTime.Minute(), pointInTime.Second(), pointInTime.Nanosecond(), time.UTC)
 }
 
+// Day normalizes the given point in time by returning a time object that represents midnight UTC
+// of the same calendar day, as seen in the given time's own location.
+func Day(pointInTime time.Time) time.Time {
+	return time.Date(pointInTime.Year(), pointInTime.Month(), pointInTime.Day(), 0, 0, 0, 0, time.UTC)
+}
+
 // NewPod returns a new pod instance for testing purposes.
 func NewPod(namespace, name string, phase v1.PodPhase) v1.Pod {
 	return v1.Pod{
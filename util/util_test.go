@@ -377,6 +377,234 @@ func (suite *Suite) TestParseDates() {
 	}
 }
 
+func (suite *Suite) TestDateRangeIncludes() {
+	holidays := NewDateRange(
+		time.Date(0, 12, 20, 0, 0, 0, 0, time.UTC),
+		time.Date(0, 1, 3, 0, 0, 0, 0, time.UTC),
+		true,
+	)
+	blackFriday := NewDateRange(
+		time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC),
+		false,
+	)
+	timezone, err := time.LoadLocation("Australia/Brisbane")
+	suite.Require().NoError(err)
+
+	for _, tt := range []struct {
+		pointInTime time.Time
+		dateRange   DateRange
+		expected    bool
+	}{
+		// start of a yearly range around the turn of the year
+		{
+			time.Date(2026, 12, 20, 0, 0, 0, 0, time.UTC),
+			holidays,
+			true,
+		},
+		// end of a yearly range around the turn of the year, any year
+		{
+			time.Date(1999, 1, 3, 23, 59, 0, 0, time.UTC),
+			holidays,
+			true,
+		},
+		// outside of a yearly range around the turn of the year
+		{
+			time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC),
+			holidays,
+			false,
+		},
+		// a specific day
+		{
+			time.Date(2026, 11, 27, 12, 0, 0, 0, time.UTC),
+			blackFriday,
+			true,
+		},
+		// the same day in another year
+		{
+			time.Date(2027, 11, 27, 12, 0, 0, 0, time.UTC),
+			blackFriday,
+			false,
+		},
+		// the day is taken in the given time's location
+		{
+			time.Date(2026, 11, 27, 1, 0, 0, 0, timezone),
+			blackFriday,
+			true,
+		},
+	} {
+		suite.Equal(tt.expected, tt.dateRange.Includes(tt.pointInTime), "%s at %s", tt.dateRange, tt.pointInTime)
+	}
+}
+
+func (suite *Suite) TestParseDateRanges() {
+	for _, tt := range []struct {
+		given    string
+		expected []DateRange
+	}{
+		// empty string
+		{
+			"",
+			[]DateRange{},
+		},
+		// yearly days and ranges
+		{
+			"Apr1, Dec20-Jan3, Dec 24 - Dec 26",
+			[]DateRange{
+				{From: time.Date(0, 4, 1, 0, 0, 0, 0, time.UTC), To: time.Date(0, 4, 1, 0, 0, 0, 0, time.UTC), Yearly: true},
+				{From: time.Date(0, 12, 20, 0, 0, 0, 0, time.UTC), To: time.Date(0, 1, 3, 0, 0, 0, 0, time.UTC), Yearly: true},
+				{From: time.Date(0, 12, 24, 0, 0, 0, 0, time.UTC), To: time.Date(0, 12, 26, 0, 0, 0, 0, time.UTC), Yearly: true},
+			},
+		},
+		// specific days and ranges
+		{
+			"2026-11-27, 2026-12-20/2027-01-03",
+			[]DateRange{
+				{From: time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC), To: time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC)},
+				{From: time.Date(2026, 12, 20, 0, 0, 0, 0, time.UTC), To: time.Date(2027, 1, 3, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+	} {
+		ranges, err := ParseDateRanges(tt.given)
+		suite.Require().NoError(err)
+
+		suite.Equal(tt.expected, ranges)
+	}
+
+	for _, invalid := range []string{"Foo1", "Dec20-Jan3-Feb4", "2027-01-03/2026-12-20", "2026-12-20/Jan3"} {
+		_, err := ParseDateRanges(invalid)
+		suite.Error(err, invalid)
+	}
+}
+
+func (suite *Suite) TestWindowIncludes() {
+	businessHours := Window{
+		Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday},
+		Period: NewTimePeriod(
+			time.Date(0, 0, 0, 10, 0, 0, 0, time.UTC),
+			time.Date(0, 0, 0, 16, 0, 0, 0, time.UTC),
+		),
+	}
+	fridayNight := Window{
+		Weekdays: []time.Weekday{time.Friday},
+		Period: NewTimePeriod(
+			time.Date(0, 0, 0, 22, 0, 0, 0, time.UTC),
+			time.Date(0, 0, 0, 2, 0, 0, 0, time.UTC),
+		),
+	}
+
+	for _, tt := range []struct {
+		pointInTime time.Time
+		window      Window
+		expected    bool
+	}{
+		// a Tuesday within business hours
+		{
+			time.Date(2026, 11, 24, 12, 0, 0, 0, time.UTC),
+			businessHours,
+			true,
+		},
+		// a Tuesday outside business hours
+		{
+			time.Date(2026, 11, 24, 17, 0, 0, 0, time.UTC),
+			businessHours,
+			false,
+		},
+		// a Friday within business hours, but not on a business day
+		{
+			time.Date(2026, 11, 27, 12, 0, 0, 0, time.UTC),
+			businessHours,
+			false,
+		},
+		// late on Friday
+		{
+			time.Date(2026, 11, 27, 23, 0, 0, 0, time.UTC),
+			fridayNight,
+			true,
+		},
+		// early on Saturday still belongs to Friday night
+		{
+			time.Date(2026, 11, 28, 1, 0, 0, 0, time.UTC),
+			fridayNight,
+			true,
+		},
+		// early on Friday belongs to Thursday night
+		{
+			time.Date(2026, 11, 27, 1, 0, 0, 0, time.UTC),
+			fridayNight,
+			false,
+		},
+		// late on Saturday
+		{
+			time.Date(2026, 11, 28, 23, 0, 0, 0, time.UTC),
+			fridayNight,
+			false,
+		},
+	} {
+		suite.Equal(tt.expected, tt.window.Includes(tt.pointInTime), "%s at %s", tt.window, tt.pointInTime)
+	}
+}
+
+func (suite *Suite) TestParseWindows() {
+	tenToFour := TimePeriod{
+		From: time.Date(0, 0, 0, 10, 0, 0, 0, time.UTC),
+		To:   time.Date(0, 0, 0, 16, 0, 0, 0, time.UTC),
+	}
+
+	for _, tt := range []struct {
+		given    string
+		expected []Window
+	}{
+		// empty string
+		{
+			"",
+			[]Window{},
+		},
+		// weekday range
+		{
+			"Mon-Thu 10:00-16:00",
+			[]Window{
+				{[]time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday}, tenToFour},
+			},
+		},
+		// weekday range around the end of the week, and a single day
+		{
+			" fri-mon 10:00-16:00 ,, wed 10:00-16:00",
+			[]Window{
+				{[]time.Weekday{time.Friday, time.Saturday, time.Sunday, time.Monday}, tenToFour},
+				{[]time.Weekday{time.Wednesday}, tenToFour},
+			},
+		},
+		// no weekdays means every day
+		{
+			"10:00-16:00",
+			[]Window{
+				{[]time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}, tenToFour},
+			},
+		},
+	} {
+		windows, err := ParseWindows(tt.given)
+		suite.Require().NoError(err)
+
+		suite.Equal(tt.expected, windows)
+	}
+
+	for _, invalid := range []string{"Mon-Thu 10:00", "Mon-Foo 10:00-16:00", "Mon-Tue-Wed 10:00-16:00", "Mon Tue 10:00-16:00"} {
+		_, err := ParseWindows(invalid)
+		suite.Error(err, invalid)
+	}
+}
+
+func (suite *Suite) TestMarshalWindow() {
+	windows, err := ParseWindows("Mon-Tue 10:00-16:00")
+	suite.NoError(err)
+
+	serialized, err := json.Marshal(windows)
+	suite.NoError(err)
+
+	suite.Equal("[\"Mon+Tue 10:00-16:00\"]", string(serialized))
+}
+
 func TestSuite(t *testing.T) {
 	suite.Run(t, new(Suite))
 }